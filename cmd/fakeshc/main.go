@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bosch-data-exporter/internal/fakeshc"
+	"context"
+	"flag"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const thermostatID = "hdm:HomeMaticIP:3014F711A0000496D858A8B1"
+
+// fakeshc runs a fake controller with one room and thermostat, whose
+// temperature changes periodically. Pair the exporter with PairingURL and
+// BaseURL set to its address.
+func main() {
+	log.Logger = log.Output(
+		zerolog.ConsoleWriter{
+			Out: os.Stdout,
+		},
+	)
+	addr := flag.String("addr", ":8444", "Address to listen on")
+	password := flag.String("password", "password", "System password for pairing")
+	interval := flag.Duration("interval", 10*time.Second, "Interval of temperature events")
+	flag.Parse()
+
+	shc := fakeshc.New(fakeshc.Options{Password: *password})
+	shc.AddRoom(fakeshc.Room{ID: "hz_1", Name: "Living room"})
+	shc.AddDevice(
+		fakeshc.Device{
+			ID:           "roomClimateControl_hz_1",
+			RoomID:       "hz_1",
+			Name:         "-RoomClimateControl-",
+			DeviceModel:  "ROOM_CLIMATE_CONTROL",
+			Manufacturer: "BOSCH",
+			Serial:       "roomClimateControl_hz_1",
+		},
+		fakeshc.Device{
+			ID:           thermostatID,
+			RootDeviceID: "64-da-a0-10-84-ad",
+			RoomID:       "hz_1",
+			Name:         "Thermostat",
+			DeviceModel:  "TRV_GEN2",
+			Manufacturer: "BOSCH",
+			Serial:       "3014F711A0000496D858A8B1",
+		},
+	)
+	temperature := 21.0
+	shc.Emit(temperatureEvent(temperature))
+	if err := shc.Listen(*addr); err != nil {
+		log.Fatal().Err(err).Str("addr", *addr).Msg("Error starting fake controller")
+	}
+	defer shc.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			//nolint:gosec // no need for secure random numbers in fake data
+			temperature += (rand.Float64() - 0.5) / 2
+			shc.Emit(temperatureEvent(temperature))
+		}
+	}
+}
+
+func temperatureEvent(temperature float64) fakeshc.Event {
+	return fakeshc.Event{
+		DeviceID:  thermostatID,
+		ServiceID: "TemperatureLevel",
+		State: map[string]interface{}{
+			"@type":       "temperatureLevelState",
+			"temperature": float64(int(temperature*10)) / 10,
+		},
+	}
+}
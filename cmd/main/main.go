@@ -2,17 +2,30 @@ package main
 
 import (
 	"bosch-data-exporter/internal/cache"
+	"bosch-data-exporter/internal/capture"
 	"bosch-data-exporter/internal/client"
 	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/control"
 	"bosch-data-exporter/internal/devices"
 	"bosch-data-exporter/internal/events"
 	"bosch-data-exporter/internal/export"
+	"bosch-data-exporter/internal/health"
+	"bosch-data-exporter/internal/heartbeat"
 	"bosch-data-exporter/internal/polling"
 	"bosch-data-exporter/internal/register"
 	"bosch-data-exporter/internal/rooms"
+	"bosch-data-exporter/internal/schedule"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -20,17 +33,57 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func main() {
+	commands := make([]string, 0)
+	args := os.Args[1:]
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		commands = append(commands, args[0])
+		args = args[1:]
+	}
+	command := strings.Join(commands, " ")
+	if command == "" {
+		command = "run"
+	}
+
+	logOut := os.Stdout
+	if command == "schedule export" {
+		// the schedule is written to stdout without -file
+		logOut = os.Stderr
+	}
 	log.Logger = log.Output(
 		zerolog.ConsoleWriter{
-			Out: os.Stdout,
+			Out: logOut,
 		},
 	)
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
-	config, err := conf.LoadConfig()
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	configPath := flags.String("config", envOrDefault("BOSCH_CONFIG", "config.json"), "Path of the json or yaml config file")
+	logLevelFlag := flags.String("log-level", "", "Log level, overrides the config")
+	port := flags.Int("port", 0, "Port of the metrics server, overrides the config")
+	capturePath := flags.String("capture", "", "Capture file. run appends the controller responses to it, replay reads them")
+	speed := flags.Float64("speed", 1, "Replay speed factor, 0 replays as fast as possible")
+	schedulePath := flags.String("file", "", "Schedule file of schedule export and schedule import, yaml or json by extension")
+	if err := flags.Parse(args); err != nil {
+		log.Fatal().Err(err).Msg("Error parsing flags")
+	}
+	if flags.NArg() > 0 {
+		log.Fatal().
+			Strs("args", flags.Args()).
+			Msg("Unexpected arguments. The command has to come before the flags, e.g. config check -config config.json")
+	}
+
+	config, err := conf.LoadConfig(*configPath)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error loading config")
+		log.Fatal().Err(err).Str("path", *configPath).Msg("Error loading config")
+	}
+	if *logLevelFlag != "" {
+		config.LogLevel = *logLevelFlag
+	}
+	if *port != 0 {
+		config.Port = *port
 	}
 	logLevel, err := zerolog.ParseLevel(config.LogLevel)
 	if err != nil {
@@ -40,42 +93,304 @@ func main() {
 	}
 	zerolog.SetGlobalLevel(logLevel)
 
+	switch command {
+	case "run":
+		if e := config.Validate(); e != nil {
+			log.Fatal().Err(e).Msg("Invalid config. Run config check for details")
+		}
+		run(config, *capturePath)
+	case "replay":
+		replay(config, *capturePath, *speed)
+	case "pair":
+		if e := config.ValidateBosch(); e != nil {
+			log.Fatal().Err(e).Msg("Invalid config. Run config check for details")
+		}
+		pair(config)
+	case "config check":
+		checkConfig(config)
+	case "schedule export":
+		exportSchedules(config, *schedulePath)
+	case "schedule import":
+		importSchedules(config, *schedulePath)
+	default:
+		log.Fatal().Str("command", command).
+			Msg("Unknown command. Use run, replay, pair, config check, schedule export or schedule import")
+	}
+}
+
+func run(config *conf.Config, capturePath string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	httpClient := client.Init(config)
+	if capturePath != "" {
+		recorder, e := capture.NewRecorder(capturePath, httpClient.Transport)
+		if e != nil {
+			log.Fatal().Err(e).Str("path", capturePath).Msg("Error opening capture file")
+		}
+		defer func() {
+			if e := recorder.Close(); e != nil {
+				log.Err(e).Msg("Error closing capture file")
+			}
+		}()
+		log.Info().Str("path", capturePath).Msg("Recording controller responses")
+		httpClient.Transport = recorder
+	}
 
-	err = register.Register(httpClient, config)
+	err := register.Register(ctx, httpClient, config)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Error registering client")
 	}
 
+	sinks, err := export.NewSinks(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Could not create export sinks")
+	}
+	fanOut := export.NewFanOut(config.SinkQueueSize, sinks...)
+	fanOut.Start()
+
+	heartbeatStore := heartbeat.New(fanOut, config)
+
 	roomPolling := rooms.NewRoomPolling(httpClient, config)
-	cachedRooms := cache.New(roomPolling.Get, time.Duration(config.DeviceUpdateInterval)*time.Minute)
+	cachedRooms := cache.New("rooms", roomPolling.Get, time.Duration(config.DeviceUpdateInterval)*time.Minute)
 
+	clock := events.NewClock(nil)
 	devicePolling := devices.NewDevicePolling(httpClient, cachedRooms, config)
-	cachedDevices := cache.New(devicePolling.Get, time.Duration(config.DeviceUpdateInterval)*time.Minute)
+	cachedDevices := cache.New(
+		"devices",
+		events.ExportDeviceStatus(devicePolling.Get, heartbeatStore, clock),
+		time.Duration(config.DeviceUpdateInterval)*time.Minute,
+	)
 
 	pollID := polling.New(httpClient, config)
-	cachedPollID := cache.New(pollID.Get, time.Minute*time.Duration(config.PollIDUpdateInterval))
+	cachedPollID := cache.New("pollID", pollID.Get, time.Minute*time.Duration(config.PollIDUpdateInterval))
 
-	exporter, err := export.NewInfluxExporter(config)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Could not connect influx client")
-	}
+	eventPolling := events.NewSmartHomeEventPolling(
+		httpClient, cachedDevices, cachedPollID, pollID, heartbeatStore, clock, config,
+	)
 
-	eventPolling := events.NewSmartHomeEventPolling(httpClient, cachedDevices, cachedPollID, exporter, config)
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		heartbeatStore.Start(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		eventPolling.Start(ctx)
+	}()
 
-	go eventPolling.Start()
+	checks := health.New()
+	checks.Add("shc", eventPolling.Reachable)
+	checks.Add("subscription", eventPolling.Subscribed)
+	checks.Add("poll", eventPolling.Recent)
+	for _, s := range sinks {
+		if c, ok := s.(health.Checker); ok {
+			checks.Add("sink_"+s.Name(), c.Health)
+		}
+	}
 
 	handler := http.NewServeMux()
 	handler.Handle("/metrics", promhttp.Handler())
+	checks.Register(handler)
+	if config.ControlConfig != nil {
+		controller := control.New(httpClient, cachedDevices, config)
+		control.NewHandler(controller, config.ControlConfig.Token).Register(handler)
+		log.Info().Msg("Control endpoints enabled")
+	}
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%d", config.Port),
 		ReadHeaderTimeout: time.Second,
 		Handler:           handler,
 	}
 
-	err = server.ListenAndServe()
-	if err != nil {
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		log.Info().Msg("Shutting down...")
+	case err = <-serverErr:
 		log.Err(err).Msg("Server failed")
+		stop()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	wg.Wait()
+	// control requests can refresh the devices, which exports device status
+	// events, so the server stops before the fan out is closed
+	if e := server.Shutdown(shutdownCtx); e != nil {
+		log.Err(e).Msg("Error shutting down server")
+	}
+	if e := pollID.Unsubscribe(shutdownCtx, cachedPollID.Current()); e != nil {
+		log.Err(e).Msg("Error removing poll subscription")
+	}
+	fanOut.Close()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// replay feeds a capture file through the event polling and the exporters.
+func replay(config *conf.Config, capturePath string, speed float64) {
+	if capturePath == "" {
+		log.Fatal().Msg("replay needs a capture file. Set it with -capture")
+	}
+	player, err := capture.Load(capturePath, speed)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", capturePath).Msg("Error loading capture")
+	}
+	if config.BoschConfig == nil {
+		config.BoschConfig = &conf.BoschConfig{}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	sinks, err := export.NewSinks(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Could not create export sinks")
+	}
+	fanOut := export.NewFanOut(config.SinkQueueSize, sinks...)
+	fanOut.Start()
+
+	roomPolling := rooms.NewRoomPolling(player, config)
+	cachedRooms := cache.New("rooms", roomPolling.Get, time.Duration(config.DeviceUpdateInterval)*time.Minute)
+	devicePolling := devices.NewDevicePolling(player, cachedRooms, config)
+	cachedDevices := cache.New("devices", devicePolling.Get, time.Duration(config.DeviceUpdateInterval)*time.Minute)
+	pollID := polling.New(player, config)
+	cachedPollID := cache.New("pollID", pollID.Get, time.Hour)
+
+	eventPolling := events.NewSmartHomeEventPolling(
+		player, cachedDevices, cachedPollID, pollID, fanOut, events.NewClock(player.Now), config,
+	)
+	pollCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-player.Done():
+			log.Info().Msg("Replayed all long polls")
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+	log.Info().Str("path", capturePath).Float64("speed", speed).Msg("Replaying capture")
+	eventPolling.Start(pollCtx)
+	fanOut.Close()
+}
+
+func pair(config *conf.Config) {
+	stdin := bufio.NewReader(os.Stdin)
+	password := os.Getenv("BOSCH_SYSTEM_PASSWORD")
+	if password == "" {
+		fmt.Fprint(os.Stderr, "System password of the controller: ")
+		line, err := stdin.ReadString('\n')
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error reading system password")
+		}
+		password = strings.TrimSpace(line)
+	}
+	fmt.Fprintln(os.Stderr, "Press the button on the controller until its LEDs flash, then press enter.")
+	if _, err := stdin.ReadString('\n'); err != nil {
+		log.Fatal().Err(err).Msg("Error waiting for confirmation")
+	}
+	if err := register.Pair(config, password); err != nil {
+		log.Fatal().Err(err).Msg("Error pairing client")
+	}
+}
+
+func checkConfig(config *conf.Config) {
+	err := errors.Join(config.Validate(), config.CheckPort())
+	if err == nil {
+		fmt.Fprintln(os.Stderr, "Config is valid")
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Config is invalid:")
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintf(os.Stderr, "  - %s\n", line)
+	}
+	os.Exit(1)
+}
+
+func newController(config *conf.Config) (*control.Controller, error) {
+	if err := config.ValidateClient(); err != nil {
+		return nil, err
+	}
+	httpClient := client.Init(config)
+	roomPolling := rooms.NewRoomPolling(httpClient, config)
+	cachedRooms := cache.New("rooms", roomPolling.Get, time.Duration(config.DeviceUpdateInterval)*time.Minute)
+	devicePolling := devices.NewDevicePolling(httpClient, cachedRooms, config)
+	cachedDevices := cache.New("devices", devicePolling.Get, time.Duration(config.DeviceUpdateInterval)*time.Minute)
+	return control.New(httpClient, cachedDevices, config), nil
+}
+
+func exportSchedules(config *conf.Config, path string) {
+	format := schedule.FormatYAML
+	if path != "" {
+		var err error
+		if format, err = schedule.FormatOf(path); err != nil {
+			log.Fatal().Err(err).Msg("Invalid schedule file")
+		}
+	}
+	controller, err := newController(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid config. Run config check for details")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	roomSchedules, err := controller.RoomSchedules(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error reading schedules")
+	}
+	out := os.Stdout
+	if path != "" {
+		out, err = os.Create(path) //nolint:gosec // the path is given by the user
+		if err != nil {
+			log.Fatal().Err(err).Str("path", path).Msg("Error creating schedule file")
+		}
+		defer func() {
+			if e := out.Close(); e != nil {
+				log.Err(e).Str("path", path).Msg("Error closing schedule file")
+			}
+		}()
+	}
+	if err = schedule.Write(out, &schedule.File{Rooms: roomSchedules}, format); err != nil {
+		log.Fatal().Err(err).Msg("Error writing schedules")
+	}
+	log.Info().Int("rooms", len(roomSchedules)).Str("path", path).Msg("Exported schedules")
+}
+
+func importSchedules(config *conf.Config, path string) {
+	if path == "" {
+		log.Fatal().Msg("Missing schedule file, use -file")
+	}
+	file, err := schedule.Read(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Schedule file is invalid:")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "  - %s\n", line)
+		}
 		os.Exit(1)
 	}
+	controller, err := newController(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid config. Run config check for details")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for _, room := range file.Rooms {
+		if e := controller.SetRoomSchedule(ctx, room); e != nil {
+			log.Fatal().Err(e).Str("room", room.Name).Msg("Error writing schedule")
+		}
+	}
+	log.Info().Int("rooms", len(file.Rooms)).Str("path", path).Msg("Imported schedules")
+}
+
+func envOrDefault(name, defaultValue string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return defaultValue
 }
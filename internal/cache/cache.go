@@ -1,30 +1,44 @@
 package cache
 
 import (
+	"bosch-data-exporter/internal/metrics"
+	"context"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 )
 
 type Cache[T interface{}] struct {
-	getNew         func() (T, error)
+	getNew         func(context.Context) (T, error)
 	currentCache   T
 	maxCacheAge    time.Duration
 	lastUpdateTime time.Time
 	lock           *sync.Mutex
+	hits           prometheus.Counter
+	refreshes      *prometheus.CounterVec
 }
 
-func New[T interface{}](getNew func() (T, error), maxCacheAge time.Duration) *Cache[T] {
+// New creates a cache. The name is used as label of the cache metrics.
+func New[T interface{}](name string, getNew func(context.Context) (T, error), maxCacheAge time.Duration) *Cache[T] {
 	return &Cache[T]{
 		lock:           &sync.Mutex{},
 		getNew:         getNew,
 		maxCacheAge:    maxCacheAge,
 		lastUpdateTime: time.Unix(0, 0),
+		hits: metrics.NewCounterVec(prometheus.CounterOpts{
+			Name: "bosch_cache_hits_total",
+			Help: "Number of reads served from a cache",
+		}, []string{"cache"}).WithLabelValues(name),
+		refreshes: metrics.NewCounterVec(prometheus.CounterOpts{
+			Name: "bosch_cache_refreshes_total",
+			Help: "Number of cache refreshes by result",
+		}, []string{"cache", "result"}).MustCurryWith(prometheus.Labels{"cache": name}),
 	}
 }
 
-func (d *Cache[T]) Get() T {
+func (d *Cache[T]) Get(ctx context.Context) T {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	age := time.Since(d.lastUpdateTime)
@@ -34,6 +48,7 @@ func (d *Cache[T]) Get() T {
 			Dur("age", age).
 			Interface("rooms", d.currentCache).
 			Msg("Using cached item")
+		d.hits.Inc()
 		return d.currentCache
 	}
 	log.Debug().
@@ -41,11 +56,26 @@ func (d *Cache[T]) Get() T {
 		Dur("maxAge", d.maxCacheAge).
 		Msg("Cached data too old. Refreshing cache...")
 	d.lastUpdateTime = time.Now()
-	newData, err := d.getNew()
+	newData, err := d.getNew(ctx)
 	if err != nil {
 		log.Err(err).Msg("Error getting new data")
+		d.refreshes.WithLabelValues("error").Inc()
 		return d.currentCache
 	}
+	d.refreshes.WithLabelValues("success").Inc()
 	d.currentCache = newData
 	return d.currentCache
 }
+
+func (d *Cache[T]) Invalidate() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.lastUpdateTime = time.Unix(0, 0)
+}
+
+// Current returns the cached item without refreshing it.
+func (d *Cache[T]) Current() T {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.currentCache
+}
@@ -1,13 +1,26 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
 )
 
+func testHits() prometheus.Counter {
+	return prometheus.NewCounter(prometheus.CounterOpts{Name: "hits"})
+}
+
+func testRefreshes() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{Name: "refreshes"}, []string{"result"})
+}
+
 func TestCache_Get(t *testing.T) {
 	type testCase struct {
 		name string
@@ -18,52 +31,76 @@ func TestCache_Get(t *testing.T) {
 		{
 			name: "cache too old",
 			d: Cache[string]{
-				getNew: func() (string, error) {
+				getNew: func(context.Context) (string, error) {
 					return "new", nil
 				},
 				currentCache:   "cache",
 				maxCacheAge:    time.Second * 5,
 				lastUpdateTime: time.Now().Add(time.Second * -10),
 				lock:           &sync.Mutex{},
+				hits:           testHits(),
+				refreshes:      testRefreshes(),
 			},
 			want: "new",
 		},
 		{
 			name: "use cache",
 			d: Cache[string]{
-				getNew: func() (string, error) {
+				getNew: func(context.Context) (string, error) {
 					return "new", nil
 				},
 				currentCache:   "cache",
 				maxCacheAge:    time.Second * 5,
 				lastUpdateTime: time.Now().Add(time.Second * -2),
 				lock:           &sync.Mutex{},
+				hits:           testHits(),
+				refreshes:      testRefreshes(),
 			},
 			want: "cache",
 		},
 		{
 			name: "error getting new",
 			d: Cache[string]{
-				getNew: func() (string, error) {
+				getNew: func(context.Context) (string, error) {
 					return "new", errors.New("test")
 				},
 				currentCache:   "cache",
 				maxCacheAge:    time.Second * 5,
 				lastUpdateTime: time.Now().Add(time.Second * -10),
 				lock:           &sync.Mutex{},
+				hits:           testHits(),
+				refreshes:      testRefreshes(),
 			},
 			want: "cache",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.d.Get(); !reflect.DeepEqual(got, tt.want) {
+			if got := tt.d.Get(context.Background()); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Get() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestCache_Invalidate(t *testing.T) {
+	calls := 0
+	d := New("test", func(context.Context) (int, error) {
+		calls++
+		return calls, nil
+	}, time.Hour)
+	d.hits = testHits()
+	d.refreshes = testRefreshes()
+
+	assert.Equal(t, 1, d.Get(context.Background()))
+	assert.Equal(t, 1, d.Get(context.Background()))
+	d.Invalidate()
+	assert.Equal(t, 2, d.Get(context.Background()))
+	assert.Equal(t, 2, d.Current())
+	assert.Equal(t, float64(1), testutil.ToFloat64(d.hits))
+	assert.Equal(t, float64(2), testutil.ToFloat64(d.refreshes.WithLabelValues("success")))
+}
+
 func BenchmarkCache_Get(b *testing.B) {
 	benchmarks := []struct {
 		name           string
@@ -74,11 +111,13 @@ func BenchmarkCache_Get(b *testing.B) {
 		{
 			name: "cached",
 			d: Cache[string]{
-				getNew: func() (string, error) {
+				getNew: func(context.Context) (string, error) {
 					return "new", errors.New("test")
 				},
 				currentCache: "cache",
 				lock:         &sync.Mutex{},
+				hits:         testHits(),
+				refreshes:    testRefreshes(),
 			},
 			maxCacheAge:    time.Second * 5,
 			lastUpdateTime: time.Now().Add(time.Second * -2),
@@ -86,11 +125,13 @@ func BenchmarkCache_Get(b *testing.B) {
 		{
 			name: "get new",
 			d: Cache[string]{
-				getNew: func() (string, error) {
+				getNew: func(context.Context) (string, error) {
 					return "new", errors.New("test")
 				},
 				currentCache: "cache",
 				lock:         &sync.Mutex{},
+				hits:         testHits(),
+				refreshes:    testRefreshes(),
 			},
 			maxCacheAge:    time.Second * 5,
 			lastUpdateTime: time.Now().Add(time.Second * -10),
@@ -104,7 +145,7 @@ func BenchmarkCache_Get(b *testing.B) {
 				bm.d.lastUpdateTime = bm.lastUpdateTime
 				b.StartTimer()
 
-				bm.d.Get()
+				bm.d.Get(context.Background())
 			}
 		})
 	}
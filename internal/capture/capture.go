@@ -0,0 +1,73 @@
+// Package capture records the responses of the controller to a JSONL file
+// and replays them, e.g. to reproduce payloads of unknown devices.
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	rpcPath      = "/remote/json-rpc"
+	longPoll     = "RE/longPoll"
+	roomsPath    = "/smarthome/rooms"
+	devicesPath  = "/smarthome/devices"
+	servicesPath = "/smarthome/devices/services"
+)
+
+// Record is one line of a capture file.
+type Record struct {
+	Time   time.Time       `json:"time"`
+	Path   string          `json:"path"`
+	Method string          `json:"method,omitempty"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+type rpcRequest struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// rpcMethod returns the JSON-RPC method of a request body.
+func rpcMethod(body []byte) string {
+	var requests []rpcRequest
+	if err := json.Unmarshal(body, &requests); err != nil || len(requests) == 0 {
+		return ""
+	}
+	return requests[0].Method
+}
+
+// readBody reads a request or response body and replaces it by a copy, so it
+// can be read again.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	content, err := io.ReadAll(*body)
+	if e := (*body).Close(); e != nil {
+		log.Err(e).Msg("Error closing body")
+	}
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(content))
+	return content, nil
+}
+
+// rawJSON keeps valid JSON as is and encodes everything else as string.
+func rawJSON(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return body
+	}
+	encoded, err := json.Marshal(string(body))
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return encoded
+}
@@ -0,0 +1,167 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rpcBody(method string) string {
+	return `[{"jsonrpc":"2.0","method":"` + method + `","params":["poll-id",30]}]`
+}
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case devicesPath:
+			_, _ = w.Write([]byte(`[{"id":"hdm:ZigBee:1"}]`))
+		case rpcPath:
+			body, _ := io.ReadAll(r.Body)
+			if rpcMethod(body) == longPoll {
+				_, _ = w.Write([]byte(`[{"result":[{"id":"PowerMeter"}],"jsonrpc":"2.0"}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"result":"poll-id","jsonrpc":"2.0"}]`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	recorder, err := NewRecorder(path, nil)
+	require.NoError(t, err)
+	client := &http.Client{Transport: recorder}
+
+	for _, req := range []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, devicesPath, ""},
+		{http.MethodPost, rpcPath, rpcBody("RE/subscribe")},
+		{http.MethodPost, rpcPath, rpcBody(longPoll)},
+		{http.MethodGet, roomsPath, ""},
+		{http.MethodGet, "/smarthome/clients", ""},
+	} {
+		request, e := http.NewRequest(req.method, server.URL+req.path, strings.NewReader(req.body))
+		require.NoError(t, e)
+		resp, e := client.Do(request)
+		require.NoError(t, e)
+		body, e := io.ReadAll(resp.Body)
+		require.NoError(t, e)
+		require.NoError(t, resp.Body.Close())
+		assert.NotEmpty(t, body, "response body has to be readable after recording")
+	}
+	require.NoError(t, recorder.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 3)
+	records := make([]Record, 0, len(lines))
+	for _, line := range lines {
+		var record Record
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	assert.Equal(t, devicesPath, records[0].Path)
+	assert.JSONEq(t, `[{"id":"hdm:ZigBee:1"}]`, string(records[0].Body))
+	assert.Equal(t, rpcPath, records[1].Path)
+	assert.Equal(t, longPoll, records[1].Method)
+	assert.Equal(t, roomsPath, records[2].Path)
+	assert.Equal(t, http.StatusNotFound, records[2].Status)
+	assert.JSONEq(t, `"not found\n"`, string(records[2].Body))
+}
+
+const testCapture = `{"time":"2026-01-02T03:04:00Z","path":"/smarthome/devices","status":200,"body":[{"id":"first"}]}
+{"time":"2026-01-02T03:04:05Z","path":"/remote/json-rpc","method":"RE/longPoll","status":200,"body":[{"result":[{"id":"a"}],"jsonrpc":"2.0"}]}
+{"time":"2026-01-02T03:04:06Z","path":"/smarthome/devices","status":200,"body":[{"id":"second"}]}
+{"time":"2026-01-02T03:04:35Z","path":"/remote/json-rpc","method":"RE/longPoll","status":200,"body":[{"result":[{"id":"b"}],"jsonrpc":"2.0"}]}
+`
+
+func TestPlayer(t *testing.T) {
+	p, err := read(strings.NewReader(testCapture), 0)
+	require.NoError(t, err)
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, start, p.Now())
+
+	do := func(ctx context.Context, method string, path string, body string) string {
+		req, e := http.NewRequestWithContext(ctx, method, "https://shc"+path, strings.NewReader(body))
+		require.NoError(t, e)
+		resp, e := p.Do(req)
+		if e != nil {
+			return e.Error()
+		}
+		content, e := io.ReadAll(resp.Body)
+		require.NoError(t, e)
+		return string(content)
+	}
+	ctx := context.Background()
+	assert.Equal(t, `[{"result":"replay","jsonrpc":"2.0"}]`, do(ctx, http.MethodPost, rpcPath, rpcBody("RE/subscribe")))
+	assert.Equal(t, `[{"id":"first"}]`, do(ctx, http.MethodGet, devicesPath, ""))
+	assert.Equal(t, "[]", do(ctx, http.MethodGet, roomsPath, ""))
+
+	assert.Equal(t, `[{"result":[{"id":"a"}],"jsonrpc":"2.0"}]`, do(ctx, http.MethodPost, rpcPath, rpcBody(longPoll)))
+	assert.Equal(t, `[{"result":[{"id":"b"}],"jsonrpc":"2.0"}]`, do(ctx, http.MethodPost, rpcPath, rpcBody(longPoll)))
+	assert.Equal(t, start.Add(30*time.Second), p.Now())
+	assert.Equal(t, `[{"id":"second"}]`, do(ctx, http.MethodGet, devicesPath, ""))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(t, context.Canceled.Error(), do(cancelled, http.MethodPost, rpcPath, rpcBody(longPoll)))
+	select {
+	case <-p.Done():
+	default:
+		assert.Fail(t, "player is not done")
+	}
+}
+
+func TestPlayer_Speed(t *testing.T) {
+	p, err := read(strings.NewReader(testCapture), 300)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		req, e := http.NewRequest(http.MethodPost, "https://shc"+rpcPath, strings.NewReader(rpcBody(longPoll)))
+		require.NoError(t, e)
+		_, e = p.Do(req)
+		require.NoError(t, e)
+	}
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 35, 0, time.UTC), p.Now())
+}
+
+func TestLoad_NoPolls(t *testing.T) {
+	_, err := read(strings.NewReader(`{"path":"/smarthome/devices","status":200,"body":[]}`), 1)
+	assert.ErrorIs(t, err, ErrNoPolls)
+}
+
+type failingBody struct {
+	closed bool
+}
+
+func (b *failingBody) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func (b *failingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestReadBody_ClosesOnError(t *testing.T) {
+	body := &failingBody{}
+	reader := io.ReadCloser(body)
+	_, err := readBody(&reader)
+	assert.Error(t, err)
+	assert.True(t, body.closed)
+}
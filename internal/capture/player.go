@@ -0,0 +1,193 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	replayPollID = "replay"
+	maxLineSize  = 16 * 1024 * 1024
+)
+
+var ErrNoPolls = errors.New("capture contains no long poll responses")
+
+// Player answers requests of the exporter with the responses of a capture
+// file. Long polls are answered in order, delayed like in the capture divided
+// by the speed. A speed of 0 replays as fast as possible.
+type Player struct {
+	records  []Record
+	speed    float64
+	lock     *sync.Mutex
+	position int
+	now      time.Time
+	done     chan struct{}
+}
+
+func Load(path string, speed float64) (*Player, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := file.Close(); e != nil {
+			log.Err(e).Str("path", path).Msg("Error closing capture file")
+		}
+	}()
+	return read(file, speed)
+}
+
+func read(r io.Reader, speed float64) (*Player, error) {
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("invalid record in line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	p := &Player{
+		records:  records,
+		speed:    speed,
+		lock:     &sync.Mutex{},
+		position: -1,
+		done:     make(chan struct{}),
+	}
+	first, ok := p.nextPoll()
+	if !ok {
+		return nil, ErrNoPolls
+	}
+	p.now = records[first].Time
+	return p, nil
+}
+
+// Now returns the capture time of the last replayed response. Events are
+// stamped with it, so replayed points land at their original time.
+func (p *Player) Now() time.Time {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.now
+}
+
+// Done is closed after the last long poll response was replayed.
+func (p *Player) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *Player) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.Path != rpcPath {
+		return p.latest(req.URL.Path), nil
+	}
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch rpcMethod(body) {
+	case "RE/subscribe":
+		return response(http.StatusOK, fmt.Sprintf(`[{"result":%q,"jsonrpc":"2.0"}]`, replayPollID)), nil
+	case longPoll:
+		return p.poll(req)
+	default:
+		return response(http.StatusOK, `[{"result":null,"jsonrpc":"2.0"}]`), nil
+	}
+}
+
+// poll returns the next long poll response. After the last one it blocks
+// until the request is cancelled.
+func (p *Player) poll(req *http.Request) (*http.Response, error) {
+	p.lock.Lock()
+	next, ok := p.nextPoll()
+	if !ok {
+		p.lock.Unlock()
+		select {
+		case <-p.done:
+		default:
+			close(p.done)
+		}
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	record := p.records[next]
+	delay := time.Duration(0)
+	if p.speed > 0 && p.position >= 0 {
+		delay = time.Duration(float64(record.Time.Sub(p.now)) / p.speed)
+	}
+	p.lock.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	p.lock.Lock()
+	p.position = next
+	p.now = record.Time
+	p.lock.Unlock()
+	return response(record.Status, string(record.Body)), nil
+}
+
+func (p *Player) nextPoll() (int, bool) {
+	for i := p.position + 1; i < len(p.records); i++ {
+		if p.records[i].Path == rpcPath && p.records[i].Method == longPoll {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// latest returns the last response for the path recorded before the current
+// position, or the first one if there is none before.
+func (p *Player) latest(path string) *http.Response {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	found := -1
+	for i := range p.records {
+		if p.records[i].Path != path {
+			continue
+		}
+		if i > p.position && found >= 0 {
+			break
+		}
+		found = i
+		if i > p.position {
+			break
+		}
+	}
+	if found < 0 {
+		return response(http.StatusOK, "[]")
+	}
+	return response(p.records[found].Status, string(p.records[found].Body))
+}
+
+func response(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
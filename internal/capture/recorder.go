@@ -0,0 +1,87 @@
+package capture
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Recorder is a http.RoundTripper that appends the long poll responses and
+// the room, device and service lists to a capture file.
+type Recorder struct {
+	next    http.RoundTripper
+	file    *os.File
+	encoder *json.Encoder
+	lock    *sync.Mutex
+}
+
+func NewRecorder(path string, next http.RoundTripper) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{
+		next:    next,
+		file:    file,
+		encoder: json.NewEncoder(file),
+		lock:    &sync.Mutex{},
+	}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := ""
+	if req.URL.Path == rpcPath {
+		body, err := readBody(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+		method = rpcMethod(body)
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil || !recorded(req.URL.Path, method) {
+		return resp, err
+	}
+	body, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.write(&Record{
+		Time:   time.Now(),
+		Path:   req.URL.Path,
+		Method: method,
+		Status: resp.StatusCode,
+		Body:   rawJSON(body),
+	})
+	return resp, nil
+}
+
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.file.Close()
+}
+
+func (r *Recorder) write(record *Record) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.encoder.Encode(record); err != nil {
+		log.Err(err).Str("path", record.Path).Msg("Error writing capture")
+	}
+}
+
+func recorded(path string, method string) bool {
+	switch path {
+	case rpcPath:
+		return method == longPoll
+	case roomsPath, devicesPath, servicesPath:
+		return true
+	}
+	return false
+}
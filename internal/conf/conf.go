@@ -3,44 +3,177 @@ package conf
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	DeviceUpdateInterval int
 	PollIDUpdateInterval int
+	HeartbeatInterval    int
 	ClientCertPath       string
 	ClientKeyPath        string
 	Port                 int
 	LogLevel             string
-	InfluxConfig         *InfluxConfig
-	BoschConfig          *BoschConfig
+	SinkQueueSize        int
+	// TimeZone is the time zone of the controller as IANA name, e.g.
+	// Europe/Berlin. The schedules of the controller are in this time zone.
+	// The default is the time zone of the host.
+	TimeZone         string
+	InfluxConfig     *InfluxConfig
+	PrometheusConfig *PrometheusConfig
+	FileConfig       *FileConfig
+	ControlConfig    *ControlConfig
+	BoschConfig      *BoschConfig
 }
 
 type BoschConfig struct {
 	ClientID   string
 	ClientName string
 	BaseURL    string
+	PairingURL string
 }
 
 type InfluxConfig struct {
-	ServerURL string
-	AuthToken string
-	Org       string
-	Bucket    string
+	ServerURL     string
+	AuthToken     string
+	AuthTokenFile string
+	Org           string
+	Bucket        string
+	// Tags lists the tags of every point: device, room, device_id, room_id,
+	// model, serial, profile, manufacturer, root_device and type. The default
+	// is device and room. Every tag is part of the series key, so renaming a
+	// device starts a new series unless only device_id and room_id are used,
+	// and changing the list starts new series for all measurements.
+	Tags []string
+	// RawArrays sets how arrays in raw_* measurements are written: drop
+	// (default), json or index.
+	RawArrays string
+	Buffer    *BufferConfig
 }
 
-func LoadConfig() (*Config, error) {
-	content, err := os.ReadFile("config.json")
+type BufferConfig struct {
+	Dir           string
+	MaxSizeMB     int
+	MaxAgeHours   int
+	FlushInterval int
+}
+
+type PrometheusConfig struct{}
+
+type FileConfig struct {
+	Path string
+}
+
+// ControlConfig enables the control endpoints. Requests have to send the
+// token as bearer token.
+type ControlConfig struct {
+	Token     string
+	TokenFile string
+}
+
+// Location returns the location of TimeZone, or the local time zone of the
+// host if TimeZone is empty or unknown.
+func (c *Config) Location() *time.Location {
+	if c.TimeZone == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
 	if err != nil {
 		return nil, err
 	}
-	log.Debug().Bytes("content", content).Msg("Loading config")
+	log.Debug().Str("path", path).Msg("Loading config")
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		content, err = yamlToJSON(content)
+		if err != nil {
+			return nil, err
+		}
+	}
 	var result Config
 	err = json.Unmarshal(content, &result)
 	if err != nil {
 		return nil, err
 	}
+	if e := applyEnv(envPrefix, reflect.ValueOf(&result).Elem()); e != nil {
+		return nil, e
+	}
+	result.resolvePaths(filepath.Dir(path))
+	if e := result.readSecrets(); e != nil {
+		return nil, e
+	}
 	return &result, nil
 }
+
+// yamlToJSON converts yaml to json, so yaml files use the same keys as json
+// files without additional struct tags.
+func yamlToJSON(content []byte) ([]byte, error) {
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal(content, &parsed); err != nil {
+		return nil, err
+	}
+	return json.Marshal(parsed)
+}
+
+// resolvePaths makes relative paths relative to the directory of the config
+// file instead of the working directory.
+func (c *Config) resolvePaths(dir string) {
+	resolve := func(path *string) {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	resolve(&c.ClientCertPath)
+	resolve(&c.ClientKeyPath)
+	if c.FileConfig != nil {
+		resolve(&c.FileConfig.Path)
+	}
+	if c.ControlConfig != nil {
+		resolve(&c.ControlConfig.TokenFile)
+	}
+	if c.InfluxConfig != nil {
+		resolve(&c.InfluxConfig.AuthTokenFile)
+		if c.InfluxConfig.Buffer != nil {
+			resolve(&c.InfluxConfig.Buffer.Dir)
+		}
+	}
+}
+
+func (c *Config) readSecrets() error {
+	if c.InfluxConfig != nil && c.InfluxConfig.AuthTokenFile != "" {
+		token, err := readSecret(c.InfluxConfig.AuthTokenFile)
+		if err != nil {
+			return err
+		}
+		c.InfluxConfig.AuthToken = token
+	}
+	if c.ControlConfig != nil && c.ControlConfig.TokenFile != "" {
+		token, err := readSecret(c.ControlConfig.TokenFile)
+		if err != nil {
+			return err
+		}
+		c.ControlConfig.Token = token
+	}
+	return nil
+}
+
+func readSecret(path string) (string, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
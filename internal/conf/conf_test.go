@@ -0,0 +1,134 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jsonConfig = `{
+  "LogLevel": "info",
+  "Port": 8080,
+  "ClientCertPath": "client-cert.pem",
+  "ClientKeyPath": "/etc/bosch/client-key.pem",
+  "InfluxConfig": {
+    "ServerURL": "http://localhost:8086",
+    "AuthToken": "adminToken",
+    "Org": "home",
+    "Bucket": "smarthome"
+  },
+  "BoschConfig": {
+    "ClientID": "oss_go_exporter",
+    "BaseURL": "https://shc1084ad:8444"
+  }
+}`
+
+const yamlConfig = `
+LogLevel: info
+Port: 8080
+ClientCertPath: client-cert.pem
+ClientKeyPath: /etc/bosch/client-key.pem
+InfluxConfig:
+  ServerURL: http://localhost:8086
+  AuthToken: adminToken
+  Org: home
+  Bucket: smarthome
+BoschConfig:
+  ClientID: oss_go_exporter
+  BaseURL: https://shc1084ad:8444
+`
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "json", file: "config.json", content: jsonConfig},
+		{name: "yaml", file: "config.yaml", content: yamlConfig},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.file, tt.content)
+
+			got, err := LoadConfig(path)
+			require.NoError(t, err)
+
+			assert.Equal(t, &Config{
+				LogLevel:       "info",
+				Port:           8080,
+				ClientCertPath: filepath.Join(filepath.Dir(path), "client-cert.pem"),
+				ClientKeyPath:  "/etc/bosch/client-key.pem",
+				InfluxConfig: &InfluxConfig{
+					ServerURL: "http://localhost:8086",
+					AuthToken: "adminToken",
+					Org:       "home",
+					Bucket:    "smarthome",
+				},
+				BoschConfig: &BoschConfig{
+					ClientID: "oss_go_exporter",
+					BaseURL:  "https://shc1084ad:8444",
+				},
+			}, got)
+		})
+	}
+}
+
+func TestLoadConfig_Env(t *testing.T) {
+	path := writeConfig(t, "config.json", jsonConfig)
+	tokenPath := writeConfig(t, "token", "secretToken\n")
+	t.Setenv("BOSCH_PORT", "9090")
+	t.Setenv("BOSCH_INFLUX_AUTHTOKEN_FILE", tokenPath)
+	t.Setenv("BOSCH_BOSCH_BASEURL", "https://shc:8444")
+	t.Setenv("BOSCH_FILE_PATH", "/var/lib/events.jsonl")
+	t.Setenv("BOSCH_PROMETHEUS", "true")
+	t.Setenv("BOSCH_INFLUX_TAGS", "model, serial")
+
+	got, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 9090, got.Port)
+	assert.Equal(t, "secretToken", got.InfluxConfig.AuthToken)
+	assert.Equal(t, []string{"model", "serial"}, got.InfluxConfig.Tags)
+	assert.Equal(t, "https://shc:8444", got.BoschConfig.BaseURL)
+	assert.Equal(t, &FileConfig{Path: "/var/lib/events.jsonl"}, got.FileConfig)
+	assert.NotNil(t, got.PrometheusConfig)
+}
+
+func TestLoadConfig_EnvDisableSection(t *testing.T) {
+	path := writeConfig(t, "config.json", jsonConfig)
+	t.Setenv("BOSCH_INFLUX", "false")
+
+	got, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Nil(t, got.InfluxConfig)
+}
+
+func TestLoadConfig_InvalidEnv(t *testing.T) {
+	path := writeConfig(t, "config.json", jsonConfig)
+	t.Setenv("BOSCH_PORT", "foo")
+
+	_, err := LoadConfig(path)
+	assert.ErrorContains(t, err, "BOSCH_PORT")
+}
+
+func TestLoadConfig_AuthTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("fileToken"), 0o600))
+	path := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("InfluxConfig:\n  AuthTokenFile: token\n"+
+		"ControlConfig:\n  TokenFile: token\n"), 0o600))
+
+	got, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fileToken", got.InfluxConfig.AuthToken)
+	assert.Equal(t, "fileToken", got.ControlConfig.Token)
+}
@@ -0,0 +1,126 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const envPrefix = "BOSCH"
+
+// applyEnv overrides config fields with environment variables. The variable
+// names are the upper case field names joined by underscores, with the Config
+// suffix of sections removed, e.g. BOSCH_INFLUX_AUTHTOKEN. Every value can also
+// be read from a file named by the same variable with a _FILE suffix. A section
+// can be enabled or disabled by setting its variable, e.g. BOSCH_PROMETHEUS, to
+// true or false.
+func applyEnv(prefix string, value reflect.Value) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := value.Field(i)
+		name := fmt.Sprintf("%s_%s", prefix, strings.ToUpper(strings.TrimSuffix(valueType.Field(i).Name, "Config")))
+		if field.Kind() == reflect.Pointer && field.Type().Elem().Kind() == reflect.Struct {
+			if err := applySectionEnv(name, field); err != nil {
+				return err
+			}
+			continue
+		}
+		raw, ok, err := lookupEnv(name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if e := setValue(field, raw); e != nil {
+			return fmt.Errorf("invalid value of %s: %w", name, e)
+		}
+	}
+	return nil
+}
+
+func applySectionEnv(name string, field reflect.Value) error {
+	if raw, ok := os.LookupEnv(name); ok {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid value of %s: %w", name, err)
+		}
+		if !enabled {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+	}
+	if field.IsNil() {
+		if !hasEnvPrefix(name + "_") {
+			return nil
+		}
+		field.Set(reflect.New(field.Type().Elem()))
+	}
+	return applyEnv(name, field.Elem())
+}
+
+func lookupEnv(name string) (string, bool, error) {
+	if path, ok := os.LookupEnv(name + "_FILE"); ok {
+		value, err := readSecret(path)
+		if err != nil {
+			return "", false, err
+		}
+		return value, true, nil
+	}
+	value, ok := os.LookupEnv(name)
+	return value, ok, nil
+}
+
+func hasEnvPrefix(prefix string) bool {
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func setValue(field reflect.Value, raw string) error {
+	//nolint:exhaustive // only kinds used in the config are supported
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(parsed))
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		values := make([]string, 0)
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const maxPort = 65535
+
+// Validate checks the whole config and returns all problems joined into one
+// error, or nil if the config is valid.
+func (c *Config) Validate() error {
+	errs := make([]error, 0)
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil {
+		addErr("LogLevel %q is invalid: %w", c.LogLevel, err)
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			addErr("TimeZone %q is invalid: %w", c.TimeZone, err)
+		}
+	}
+	if c.DeviceUpdateInterval < 1 {
+		addErr("DeviceUpdateInterval must be at least 1 minute, but is %d", c.DeviceUpdateInterval)
+	}
+	if c.PollIDUpdateInterval < 1 {
+		addErr("PollIDUpdateInterval must be at least 1 minute, but is %d", c.PollIDUpdateInterval)
+	}
+	if c.HeartbeatInterval < 0 {
+		addErr("HeartbeatInterval must not be negative, but is %d", c.HeartbeatInterval)
+	}
+	if c.SinkQueueSize < 0 {
+		addErr("SinkQueueSize must not be negative, but is %d", c.SinkQueueSize)
+	}
+	if c.Port < 1 || c.Port > maxPort {
+		addErr("Port must be between 1 and %d, but is %d", maxPort, c.Port)
+	}
+	errs = append(errs, c.validateClient()...)
+
+	if c.InfluxConfig == nil && c.PrometheusConfig == nil && c.FileConfig == nil {
+		addErr("no export sink configured. Add at least one of InfluxConfig, PrometheusConfig or FileConfig")
+	}
+	if c.InfluxConfig != nil {
+		errs = append(errs, c.InfluxConfig.validate()...)
+	}
+	if c.FileConfig != nil && c.FileConfig.Path == "" {
+		addErr("FileConfig.Path is missing")
+	}
+	if c.ControlConfig != nil && c.ControlConfig.Token == "" {
+		addErr("ControlConfig.Token is missing")
+	}
+	return errors.Join(errs...)
+}
+
+// CheckPort checks that Port, which serves the metrics, health and control
+// endpoints, can be bound. It is not part of Validate, a running exporter has
+// bound the port already and run fails by itself if it is taken.
+func (c *Config) CheckPort() error {
+	if c.Port < 1 || c.Port > maxPort {
+		// reported by Validate
+		return nil
+	}
+	if err := checkPortFree(c.Port); err != nil {
+		return fmt.Errorf("Port %d is not available: %w", c.Port, err)
+	}
+	return nil
+}
+
+// ValidateBosch checks the BoschConfig, which pair needs. pair creates the
+// client certificate, so it is not checked.
+func (c *Config) ValidateBosch() error {
+	return errors.Join(c.validateBosch()...)
+}
+
+// ValidateClient checks the BoschConfig and the client certificate, which
+// commands need that only talk to the controller.
+func (c *Config) ValidateClient() error {
+	return errors.Join(c.validateClient()...)
+}
+
+func (c *Config) validateBosch() []error {
+	if c.BoschConfig == nil {
+		return []error{errors.New("BoschConfig is missing")}
+	}
+	return c.BoschConfig.validate()
+}
+
+func (c *Config) validateClient() []error {
+	errs := make([]error, 0)
+	if err := checkReadable(c.ClientCertPath); err != nil {
+		errs = append(errs, fmt.Errorf("ClientCertPath is not readable: %w", err))
+	}
+	if err := checkReadable(c.ClientKeyPath); err != nil {
+		errs = append(errs, fmt.Errorf("ClientKeyPath is not readable: %w", err))
+	}
+	return append(errs, c.validateBosch()...)
+}
+
+func (c *BoschConfig) validate() []error {
+	errs := make([]error, 0)
+	if c.ClientID == "" {
+		errs = append(errs, errors.New("BoschConfig.ClientID is missing"))
+	}
+	if err := checkURL(c.BaseURL); err != nil {
+		errs = append(errs, fmt.Errorf("BoschConfig.BaseURL is invalid: %w", err))
+	}
+	if c.PairingURL != "" {
+		if err := checkURL(c.PairingURL); err != nil {
+			errs = append(errs, fmt.Errorf("BoschConfig.PairingURL is invalid: %w", err))
+		}
+	}
+	return errs
+}
+
+func (c *InfluxConfig) validate() []error {
+	errs := make([]error, 0)
+	if err := checkURL(c.ServerURL); err != nil {
+		errs = append(errs, fmt.Errorf("InfluxConfig.ServerURL is invalid: %w", err))
+	}
+	if c.AuthToken == "" {
+		errs = append(errs, errors.New("InfluxConfig.AuthToken is missing"))
+	}
+	if c.Org == "" {
+		errs = append(errs, errors.New("InfluxConfig.Org is missing"))
+	}
+	if c.Bucket == "" {
+		errs = append(errs, errors.New("InfluxConfig.Bucket is missing"))
+	}
+	switch c.RawArrays {
+	case "", "drop", "json", "index":
+	default:
+		errs = append(errs, fmt.Errorf("InfluxConfig.RawArrays %q is invalid, must be drop, json or index", c.RawArrays))
+	}
+	for _, tag := range c.Tags {
+		if !IsTag(tag) {
+			errs = append(errs, fmt.Errorf("InfluxConfig.Tags contains unknown tag %q", tag))
+		}
+	}
+	if len(c.Tags) > 0 && !slices.Contains(c.Tags, "device") && !slices.Contains(c.Tags, "device_id") {
+		errs = append(errs, errors.New("InfluxConfig.Tags must contain device or device_id"))
+	}
+	if c.Buffer == nil {
+		return errs
+	}
+	if c.Buffer.Dir == "" {
+		errs = append(errs, errors.New("InfluxConfig.Buffer.Dir is missing"))
+	}
+	if c.Buffer.MaxSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("InfluxConfig.Buffer.MaxSizeMB must not be negative, but is %d", c.Buffer.MaxSizeMB))
+	}
+	if c.Buffer.MaxAgeHours < 0 {
+		errs = append(errs, fmt.Errorf("InfluxConfig.Buffer.MaxAgeHours must not be negative, but is %d", c.Buffer.MaxAgeHours))
+	}
+	if c.Buffer.FlushInterval < 0 {
+		errs = append(errs, fmt.Errorf("InfluxConfig.Buffer.FlushInterval must not be negative, but is %d", c.Buffer.FlushInterval))
+	}
+	return errs
+}
+
+func checkURL(raw string) error {
+	if raw == "" {
+		return errors.New("url is missing")
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("scheme of %q must be http or https", raw)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("host of %q is missing", raw)
+	}
+	return nil
+}
+
+func checkReadable(path string) error {
+	if path == "" {
+		return errors.New("path is missing")
+	}
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func checkPortFree(port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return listener.Close()
+}
+
+// DefaultTags returns the tags used if InfluxConfig.Tags is empty.
+func DefaultTags() []string {
+	return []string{"device", "room"}
+}
+
+// IsTag reports whether name is a tag supported by InfluxConfig.Tags.
+func IsTag(name string) bool {
+	switch name {
+	case "device", "room", "device_id", "room_id",
+		"model", "serial", "profile", "manufacturer", "root_device", "type":
+		return true
+	}
+	return false
+}
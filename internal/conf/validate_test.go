@@ -0,0 +1,134 @@
+package conf
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig(t *testing.T) *Config {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client-cert.pem")
+	keyPath := filepath.Join(dir, "client-key.pem")
+	require.NoError(t, os.WriteFile(certPath, []byte("cert"), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, []byte("key"), 0o600))
+	return &Config{
+		DeviceUpdateInterval: 10,
+		PollIDUpdateInterval: 30,
+		ClientCertPath:       certPath,
+		ClientKeyPath:        keyPath,
+		Port:                 8080,
+		LogLevel:             "info",
+		PrometheusConfig:     &PrometheusConfig{},
+		BoschConfig: &BoschConfig{
+			ClientID: "oss_go_exporter",
+			BaseURL:  "https://shc1084ad:8444",
+		},
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, validConfig(t).Validate())
+}
+
+func TestConfig_Validate_AllProblems(t *testing.T) {
+	config := validConfig(t)
+	config.DeviceUpdateInterval = 0
+	config.LogLevel = "loud"
+	config.TimeZone = "Europe/Atlantis"
+	config.ClientCertPath = filepath.Join(t.TempDir(), "missing.pem")
+	config.BoschConfig = nil
+	config.PrometheusConfig = nil
+	config.ControlConfig = &ControlConfig{}
+	config.InfluxConfig = &InfluxConfig{
+		ServerURL: "localhost:8086",
+		Org:       "home",
+		Bucket:    "smarthome",
+		Tags:      []string{"model", "colour"},
+		RawArrays: "csv",
+		Buffer:    &BufferConfig{MaxSizeMB: -1},
+	}
+
+	err := config.Validate()
+	require.Error(t, err)
+	for _, problem := range []string{
+		"LogLevel \"loud\" is invalid",
+		"TimeZone \"Europe/Atlantis\" is invalid",
+		"DeviceUpdateInterval must be at least 1 minute, but is 0",
+		"ClientCertPath is not readable",
+		"BoschConfig is missing",
+		"InfluxConfig.ServerURL is invalid",
+		"InfluxConfig.AuthToken is missing",
+		"ControlConfig.Token is missing",
+		"InfluxConfig.Tags contains unknown tag \"colour\"",
+		"InfluxConfig.Tags must contain device or device_id",
+		"InfluxConfig.RawArrays \"csv\" is invalid",
+		"InfluxConfig.Buffer.Dir is missing",
+		"InfluxConfig.Buffer.MaxSizeMB must not be negative",
+	} {
+		assert.ErrorContains(t, err, problem)
+	}
+	assert.NotContains(t, err.Error(), "no export sink configured")
+}
+
+func TestConfig_Location(t *testing.T) {
+	tests := []struct {
+		timeZone string
+		want     *time.Location
+	}{
+		{"", time.Local},
+		{"Europe/Atlantis", time.Local},
+		{"UTC", time.UTC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timeZone, func(t *testing.T) {
+			config := Config{TimeZone: tt.timeZone}
+			assert.Equal(t, tt.want, config.Location())
+		})
+	}
+	berlin := Config{TimeZone: "Europe/Berlin"}
+	assert.Equal(t, "Europe/Berlin", berlin.Location().String())
+}
+
+func TestConfig_Validate_NoSink(t *testing.T) {
+	config := validConfig(t)
+	config.PrometheusConfig = nil
+
+	assert.ErrorContains(t, config.Validate(), "no export sink configured")
+}
+
+func TestConfig_CheckPort(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	config := validConfig(t)
+	config.Port = listener.Addr().(*net.TCPAddr).Port
+
+	assert.ErrorContains(t, config.CheckPort(), "is not available")
+	assert.NoError(t, config.Validate(), "a running exporter must not fail the check of its config")
+
+	require.NoError(t, listener.Close())
+	assert.NoError(t, config.CheckPort())
+
+	config.Port = 0
+	assert.NoError(t, config.CheckPort(), "an invalid port is reported by Validate")
+}
+
+func TestConfig_ValidateClient(t *testing.T) {
+	config := validConfig(t)
+	config.PrometheusConfig = nil
+	config.Port = 0
+	assert.NoError(t, config.ValidateClient())
+	assert.NoError(t, config.ValidateBosch())
+
+	config.ClientCertPath = filepath.Join(t.TempDir(), "missing.pem")
+	assert.ErrorContains(t, config.ValidateClient(), "ClientCertPath is not readable")
+	assert.NoError(t, config.ValidateBosch())
+
+	config.BoschConfig = nil
+	assert.ErrorContains(t, config.ValidateBosch(), "BoschConfig is missing")
+}
@@ -0,0 +1,143 @@
+package control
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/devices"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	roomClimateControlModel = "ROOM_CLIMATE_CONTROL"
+	minSetpoint             = 5.0
+	maxSetpoint             = 30.0
+	setpointStep            = 0.5
+)
+
+var (
+	ErrInvalidState = errors.New("invalid state")
+	ErrNotFound     = errors.New("room climate control not found")
+)
+
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type devicePolling interface {
+	Get(ctx context.Context) []*devices.Device
+}
+
+// ClimateState is a change of a RoomClimateControl. Fields that are not set
+// keep their current value.
+type ClimateState struct {
+	SetpointTemperature *float64 `json:"setpointTemperature,omitempty"`
+	OperationMode       string   `json:"operationMode,omitempty"`
+	BoostMode           *bool    `json:"boostMode,omitempty"`
+}
+
+type climateControlState struct {
+	Type string `json:"@type"`
+	ClimateState
+}
+
+// Controller changes the state of devices at the controller.
+type Controller struct {
+	client  httpClient
+	devices devicePolling
+	baseURL string
+}
+
+func New(client httpClient, devicePolling devicePolling, config *conf.Config) *Controller {
+	return &Controller{
+		client:  client,
+		devices: devicePolling,
+		baseURL: config.BoschConfig.BaseURL,
+	}
+}
+
+func (s *ClimateState) validate() error {
+	if s.SetpointTemperature == nil && s.OperationMode == "" && s.BoostMode == nil {
+		return fmt.Errorf("%w: no change", ErrInvalidState)
+	}
+	if s.SetpointTemperature != nil {
+		t := *s.SetpointTemperature
+		if t < minSetpoint || t > maxSetpoint || math.Mod(t, setpointStep) != 0 {
+			return fmt.Errorf("%w: setpointTemperature must be between %.1f and %.1f in steps of %.1f, but is %v",
+				ErrInvalidState, minSetpoint, maxSetpoint, setpointStep, t)
+		}
+	}
+	switch s.OperationMode {
+	case "", "AUTOMATIC", "MANUAL":
+	default:
+		return fmt.Errorf("%w: operationMode must be AUTOMATIC or MANUAL, but is %q", ErrInvalidState, s.OperationMode)
+	}
+	return nil
+}
+
+// RoomClimateControl returns the climate control device of a room.
+func (c *Controller) RoomClimateControl(ctx context.Context, roomID string) (*devices.Device, error) {
+	for _, d := range c.devices.Get(ctx) {
+		if d.DeviceModel == roomClimateControlModel && d.Room != nil && d.Room.ID == roomID {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: room %s", ErrNotFound, roomID)
+}
+
+// SetRoomClimate changes setpoint, operation mode or boost mode of the
+// climate control of a room.
+func (c *Controller) SetRoomClimate(ctx context.Context, roomID string, state ClimateState) error {
+	if err := state.validate(); err != nil {
+		return err
+	}
+	device, err := c.RoomClimateControl(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(climateControlState{Type: "climateControlState", ClimateState: state})
+	if err != nil {
+		return err
+	}
+	log.Info().
+		Str("room", device.Room.Name).
+		Str("deviceId", device.ID).
+		Bytes("state", body).
+		Msg("Changing room climate")
+	return c.putState(ctx, device, body)
+}
+
+func (c *Controller) stateURL(device *devices.Device) string {
+	return fmt.Sprintf("%s/smarthome/devices/%s/services/RoomClimateControl/state", c.baseURL, device.ID)
+}
+
+func (c *Controller) putState(ctx context.Context, device *devices.Device, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.stateURL(device), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := resp.Body.Close()
+		if e != nil {
+			log.Err(e).Msg("Error closing response body")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("response status of put state call is %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
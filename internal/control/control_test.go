@@ -0,0 +1,303 @@
+package control
+
+import (
+	"bosch-data-exporter/internal/devices"
+	"bosch-data-exporter/internal/rooms"
+	"bosch-data-exporter/internal/schedule"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockClient struct {
+	mockDo func(*http.Request) (*http.Response, error)
+}
+
+func (m *mockClient) Do(request *http.Request) (*http.Response, error) {
+	return m.mockDo(request)
+}
+
+type mockDevices struct{}
+
+func (m *mockDevices) Get(context.Context) []*devices.Device {
+	return []*devices.Device{
+		{ID: "hdm:HomeMaticIP:1", DeviceModel: "TRV_GEN2", Room: &rooms.Room{ID: "hz_1", Name: "Kitchen"}},
+		{ID: "roomClimateControl_hz_1", DeviceModel: "ROOM_CLIMATE_CONTROL", Room: &rooms.Room{ID: "hz_1", Name: "Kitchen"}},
+	}
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestController_SetRoomClimate(t *testing.T) {
+	boost := true
+	tests := []struct {
+		name     string
+		roomID   string
+		state    ClimateState
+		status   int
+		wantBody string
+		wantErr  error
+	}{
+		{
+			name:     "setpoint",
+			roomID:   "hz_1",
+			state:    ClimateState{SetpointTemperature: float(21.5)},
+			status:   http.StatusNoContent,
+			wantBody: `{"@type":"climateControlState","setpointTemperature":21.5}`,
+		},
+		{
+			name:     "mode and boost",
+			roomID:   "hz_1",
+			state:    ClimateState{OperationMode: "MANUAL", BoostMode: &boost},
+			status:   http.StatusOK,
+			wantBody: `{"@type":"climateControlState","operationMode":"MANUAL","boostMode":true}`,
+		},
+		{
+			name:    "no change",
+			roomID:  "hz_1",
+			wantErr: ErrInvalidState,
+		},
+		{
+			name:    "setpoint out of range",
+			roomID:  "hz_1",
+			state:   ClimateState{SetpointTemperature: float(35)},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name:    "setpoint step",
+			roomID:  "hz_1",
+			state:   ClimateState{SetpointTemperature: float(21.3)},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name:    "invalid mode",
+			roomID:  "hz_1",
+			state:   ClimateState{OperationMode: "ECO"},
+			wantErr: ErrInvalidState,
+		},
+		{
+			name:    "unknown room",
+			roomID:  "hz_2",
+			state:   ClimateState{OperationMode: "AUTOMATIC"},
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			c := &Controller{
+				client: &mockClient{func(request *http.Request) (*http.Response, error) {
+					called = true
+					assert.Equal(t, http.MethodPut, request.Method)
+					assert.Equal(t,
+						"http://localhost:8080/smarthome/devices/roomClimateControl_hz_1/services/RoomClimateControl/state",
+						request.URL.String(),
+					)
+					body, err := io.ReadAll(request.Body)
+					assert.NoError(t, err)
+					assert.JSONEq(t, tt.wantBody, string(body))
+					return &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(""))}, nil
+				}},
+				devices: &mockDevices{},
+				baseURL: "http://localhost:8080",
+			}
+			err := c.SetRoomClimate(context.Background(), tt.roomID, tt.state)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.False(t, called)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, called)
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		err    error
+		status int
+	}{
+		{
+			name:   "set",
+			method: http.MethodPut,
+			path:   "/control/rooms/hz_1/climate",
+			token:  "secret",
+			body:   `{"setpointTemperature":20}`,
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "missing token",
+			method: http.MethodPut,
+			path:   "/control/rooms/hz_1/climate",
+			body:   `{"setpointTemperature":20}`,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong token",
+			method: http.MethodPut,
+			path:   "/control/rooms/hz_1/climate",
+			token:  "guess",
+			body:   `{"setpointTemperature":20}`,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "unknown path",
+			method: http.MethodPut,
+			path:   "/control/rooms/hz_1",
+			token:  "secret",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "wrong method",
+			method: http.MethodGet,
+			path:   "/control/rooms/hz_1/climate",
+			token:  "secret",
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			name:   "unknown field",
+			method: http.MethodPut,
+			path:   "/control/rooms/hz_1/climate",
+			token:  "secret",
+			body:   `{"temperature":20}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown room",
+			method: http.MethodPut,
+			path:   "/control/rooms/hz_9/climate",
+			token:  "secret",
+			body:   `{"setpointTemperature":20}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "controller error",
+			method: http.MethodPut,
+			path:   "/control/rooms/hz_1/climate",
+			token:  "secret",
+			body:   `{"setpointTemperature":20}`,
+			err:    errors.New("connection refused"),
+			status: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{
+				client: &mockClient{func(request *http.Request) (*http.Response, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &http.Response{StatusCode: http.StatusNoContent, Body: io.NopCloser(strings.NewReader(""))}, nil
+				}},
+				devices: &mockDevices{},
+				baseURL: "http://localhost:8080",
+			}
+			mux := http.NewServeMux()
+			NewHandler(c, "secret").Register(mux)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, req)
+			assert.Equal(t, tt.status, recorder.Code)
+		})
+	}
+}
+
+func TestController_RoomSchedules(t *testing.T) {
+	c := &Controller{
+		client: &mockClient{func(request *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodGet, request.Method)
+			assert.Equal(t,
+				"http://localhost:8080/smarthome/devices/roomClimateControl_hz_1/services/RoomClimateControl/state",
+				request.URL.String(),
+			)
+			body := `{"@type":"climateControlState","setpointTemperature":21,` +
+				`"setpointTemperatureForLevelComfort":21.5,"setpointTemperatureForLevelEco":17,` +
+				`"schedule":{"profiles":[{"day":"MONDAY","switchPoints":[` +
+				`{"startTimeMinutes":1320,"value":{"@type":"temperatureLevelSwitchPointValue","temperatureLevel":"ECO"}},` +
+				`{"startTimeMinutes":390,"value":{"@type":"temperatureLevelSwitchPointValue","temperatureLevel":"COMFORT"}}]}]}}`
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+		}},
+		devices: &mockDevices{},
+		baseURL: "http://localhost:8080",
+	}
+	result, err := c.RoomSchedules(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []schedule.Room{{
+		ID:                 "hz_1",
+		Name:               "Kitchen",
+		ComfortTemperature: 21.5,
+		EcoTemperature:     17,
+		Days: []schedule.Day{{
+			Day:          "MONDAY",
+			SwitchPoints: []schedule.Point{{Time: "06:30", Level: "COMFORT"}, {Time: "22:00", Level: "ECO"}},
+		}},
+	}}, result)
+}
+
+func TestController_SetRoomSchedule(t *testing.T) {
+	days := make([]schedule.Day, 0, 7)
+	for _, d := range schedule.Days() {
+		days = append(days, schedule.Day{Day: d, SwitchPoints: []schedule.Point{{Time: "00:00", Level: "ECO"}}})
+	}
+	tests := []struct {
+		name    string
+		room    schedule.Room
+		wantErr error
+	}{
+		{name: "by id", room: schedule.Room{ID: "hz_1", EcoTemperature: 16, Days: days}},
+		{name: "by name", room: schedule.Room{ID: "hz_7", Name: "Kitchen", EcoTemperature: 16, Days: days}},
+		{name: "unknown room", room: schedule.Room{ID: "hz_7", Name: "Bath", Days: days}, wantErr: ErrNotFound},
+		{name: "invalid", room: schedule.Room{ID: "hz_1", Days: days[1:]}, wantErr: ErrInvalidState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			c := &Controller{
+				client: &mockClient{func(request *http.Request) (*http.Response, error) {
+					called = true
+					assert.Equal(t, http.MethodPut, request.Method)
+					assert.Equal(t,
+						"http://localhost:8080/smarthome/devices/roomClimateControl_hz_1/services/RoomClimateControl/state",
+						request.URL.String(),
+					)
+					var body map[string]interface{}
+					assert.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+					assert.Equal(t, "climateControlState", body["@type"])
+					assert.Equal(t, 16.0, body["setpointTemperatureForLevelEco"])
+					assert.NotContains(t, body, "setpointTemperatureForLevelComfort")
+					assert.Len(t, body["schedule"].(map[string]interface{})["profiles"], 7)
+					return &http.Response{StatusCode: http.StatusNoContent, Body: io.NopCloser(strings.NewReader(""))}, nil
+				}},
+				devices: &mockDevices{},
+				baseURL: "http://localhost:8080",
+			}
+			err := c.SetRoomSchedule(context.Background(), tt.room)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.False(t, called)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, called)
+		})
+	}
+}
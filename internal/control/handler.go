@@ -0,0 +1,82 @@
+package control
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const roomsPrefix = "/control/rooms/"
+
+// Handler serves PUT /control/rooms/{roomID}/climate. Requests need the
+// configured token as bearer token.
+type Handler struct {
+	controller *Controller
+	token      string
+}
+
+func NewHandler(controller *Controller, token string) *Handler {
+	return &Handler{
+		controller: controller,
+		token:      token,
+	}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.Handle(roomsPrefix, h)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+	roomID, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, roomsPrefix), "/climate")
+	if !ok || roomID == "" || strings.Contains(roomID, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPut {
+		w.Header().Set("Allow", http.MethodPut)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var state ClimateState
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&state); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := h.controller.SetRoomClimate(r.Context(), roomID, state)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, ErrInvalidState):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Err(err).Str("room", roomID).Msg("Error changing room climate")
+		writeError(w, http.StatusBadGateway, err.Error())
+	}
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && h.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": message}); err != nil {
+		log.Err(err).Msg("Error writing response")
+	}
+}
@@ -0,0 +1,112 @@
+package control
+
+import (
+	"bosch-data-exporter/internal/devices"
+	"bosch-data-exporter/internal/schedule"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+type scheduleState struct {
+	Type                               string            `json:"@type"`
+	Schedule                           schedule.Schedule `json:"schedule"`
+	SetpointTemperatureForLevelComfort float64           `json:"setpointTemperatureForLevelComfort,omitempty"`
+	SetpointTemperatureForLevelEco     float64           `json:"setpointTemperatureForLevelEco,omitempty"`
+}
+
+// RoomSchedules returns the weekly schedule of every room with a climate
+// control.
+func (c *Controller) RoomSchedules(ctx context.Context) ([]schedule.Room, error) {
+	result := make([]schedule.Room, 0)
+	for _, d := range c.devices.Get(ctx) {
+		if d.DeviceModel != roomClimateControlModel || d.Room == nil {
+			continue
+		}
+		state, err := c.getState(ctx, d)
+		if err != nil {
+			return nil, fmt.Errorf("error reading schedule of room %s: %w", d.Room.Name, err)
+		}
+		result = append(result, schedule.Room{
+			ID:                 d.Room.ID,
+			Name:               d.Room.Name,
+			ComfortTemperature: state.SetpointTemperatureForLevelComfort,
+			EcoTemperature:     state.SetpointTemperatureForLevelEco,
+			Days:               schedule.FromSchedule(state.Schedule),
+		})
+	}
+	return result, nil
+}
+
+// SetRoomSchedule writes the weekly schedule and the temperatures of the
+// levels of a room. The room is looked up by ID and then by name.
+func (c *Controller) SetRoomSchedule(ctx context.Context, room schedule.Room) error {
+	s, err := room.Schedule()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+	device, err := c.findRoomClimateControl(ctx, room.ID, room.Name)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(scheduleState{
+		Type:                               "climateControlState",
+		Schedule:                           s,
+		SetpointTemperatureForLevelComfort: room.ComfortTemperature,
+		SetpointTemperatureForLevelEco:     room.EcoTemperature,
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().
+		Str("room", device.Room.Name).
+		Str("deviceId", device.ID).
+		Msg("Changing room schedule")
+	return c.putState(ctx, device, body)
+}
+
+func (c *Controller) findRoomClimateControl(ctx context.Context, roomID string, name string) (*devices.Device, error) {
+	device, err := c.RoomClimateControl(ctx, roomID)
+	if err == nil || name == "" {
+		return device, err
+	}
+	for _, d := range c.devices.Get(ctx) {
+		if d.DeviceModel == roomClimateControlModel && d.Room != nil && d.Room.Name == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: room %s (%s)", ErrNotFound, name, roomID)
+}
+
+func (c *Controller) getState(ctx context.Context, device *devices.Device) (*scheduleState, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.stateURL(device), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := resp.Body.Close()
+		if e != nil {
+			log.Err(e).Msg("Error closing response body")
+		}
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response status of get state call is %d: %s", resp.StatusCode, body)
+	}
+	state := &scheduleState{}
+	if err = json.Unmarshal(body, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -19,7 +19,7 @@ type httpClient interface {
 }
 
 type currentRooms interface {
-	Get() []*rooms.Room
+	Get(ctx context.Context) []*rooms.Room
 }
 
 type DeviceResponse struct {
@@ -38,13 +38,16 @@ type DeviceResponse struct {
 }
 
 type Device struct {
-	Type        string
-	ID          string
-	DeviceModel string
-	Serial      string
-	Name        string
-	Profile     string
-	Room        *rooms.Room
+	Type         string
+	ID           string
+	RootDeviceID string
+	DeviceModel  string
+	Manufacturer string
+	Serial       string
+	Name         string
+	Profile      string
+	Status       string
+	Room         *rooms.Room
 }
 
 type DevicePolling struct {
@@ -80,10 +83,10 @@ func NewDevicePolling(client httpClient, currentRooms currentRooms, config *conf
 	}
 }
 
-func (d *DevicePolling) Get() ([]*Device, error) {
+func (d *DevicePolling) Get(ctx context.Context) ([]*Device, error) {
 	log.Debug().Msg("Getting devices...")
 	req, err := http.NewRequestWithContext(
-		context.Background(),
+		ctx,
 		http.MethodGet,
 		fmt.Sprintf("%s/smarthome/devices", d.baseURL),
 		nil,
@@ -128,7 +131,7 @@ func (d *DevicePolling) Get() ([]*Device, error) {
 			Str("roomId", jsonBody[i].RoomID).
 			Str("serial", jsonBody[i].Serial).
 			Msg("Got device")
-		room := d.getRoom(jsonBody[i].RoomID)
+		room := d.getRoom(ctx, jsonBody[i].RoomID)
 		if room == nil {
 			log.Error().
 				Str("roomID", jsonBody[i].RoomID).
@@ -140,13 +143,16 @@ func (d *DevicePolling) Get() ([]*Device, error) {
 		devices = append(
 			devices,
 			&Device{
-				Type:        jsonBody[i].Type,
-				ID:          jsonBody[i].ID,
-				DeviceModel: jsonBody[i].DeviceModel,
-				Serial:      jsonBody[i].Serial,
-				Name:        jsonBody[i].Name,
-				Profile:     jsonBody[i].Profile,
-				Room:        room,
+				Type:         jsonBody[i].Type,
+				ID:           jsonBody[i].ID,
+				RootDeviceID: jsonBody[i].RootDeviceID,
+				DeviceModel:  jsonBody[i].DeviceModel,
+				Manufacturer: jsonBody[i].Manufacturer,
+				Serial:       jsonBody[i].Serial,
+				Name:         jsonBody[i].Name,
+				Profile:      jsonBody[i].Profile,
+				Status:       jsonBody[i].Status,
+				Room:         room,
 			},
 		)
 	}
@@ -154,8 +160,8 @@ func (d *DevicePolling) Get() ([]*Device, error) {
 	return devices, nil
 }
 
-func (d *DevicePolling) getRoom(id string) *rooms.Room {
-	for _, r := range d.rooms.Get() {
+func (d *DevicePolling) getRoom(ctx context.Context, id string) *rooms.Room {
+	for _, r := range d.rooms.Get(ctx) {
 		if r.ID == id {
 			return r
 		}
@@ -2,6 +2,7 @@ package devices
 
 import (
 	"bosch-data-exporter/internal/rooms"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -47,7 +48,7 @@ type mockCurrentRooms struct {
 	mockGet func() []*rooms.Room
 }
 
-func (m *mockCurrentRooms) Get() []*rooms.Room {
+func (m *mockCurrentRooms) Get(context.Context) []*rooms.Room {
 	return m.mockGet()
 }
 
@@ -78,12 +79,15 @@ func TestDevicePolling_Get(t *testing.T) {
 			},
 			want: []*Device{
 				{
-					Type:        "device",
-					ID:          "roomClimateControl_hz_4",
-					DeviceModel: "ROOM_CLIMATE_CONTROL",
-					Serial:      "roomClimateControl_hz_4",
-					Name:        "-RoomClimateControl-",
-					Profile:     "",
+					Type:         "device",
+					ID:           "roomClimateControl_hz_4",
+					RootDeviceID: "64-da-a0-10-84-ad",
+					DeviceModel:  "ROOM_CLIMATE_CONTROL",
+					Manufacturer: "BOSCH",
+					Serial:       "roomClimateControl_hz_4",
+					Name:         "-RoomClimateControl-",
+					Profile:      "",
+					Status:       "AVAILABLE",
 					Room: &rooms.Room{
 						ID:   "hz_4",
 						Name: "Schlafzimmer",
@@ -163,7 +167,7 @@ func TestDevicePolling_Get(t *testing.T) {
 				},
 			}
 
-			got, err := r.Get()
+			got, err := r.Get(context.Background())
 			if !tt.wantErr(t, err, fmt.Sprintf("getSingle(%s)", tt.name)) {
 				return
 			}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock hands out receive times for events. The times are strictly
+// increasing, so two events of the same service never share a timestamp and
+// overwrite each other in influx. All events of one exporter have to take
+// their times from the same clock.
+type Clock struct {
+	lock sync.Mutex
+	now  func() time.Time
+	last time.Time
+}
+
+// NewClock returns a clock reading the time from now, e.g. the capture time
+// when replaying recorded traffic. nil reads the current time.
+func NewClock(now func() time.Time) *Clock {
+	if now == nil {
+		now = time.Now
+	}
+	return &Clock{now: now}
+}
+
+// read returns the current time without handing it out.
+func (c *Clock) read() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now()
+}
+
+// reserve hands out n consecutive nanoseconds starting at t, or right after
+// the last handed out time if t is not after it. It returns the first one.
+func (c *Clock) reserve(t time.Time, n int) time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !t.After(c.last) {
+		t = c.last.Add(time.Nanosecond)
+	}
+	if n > 0 {
+		c.last = t.Add(time.Duration(n-1) * time.Nanosecond)
+	}
+	return t
+}
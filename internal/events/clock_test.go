@@ -0,0 +1,21 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock_reserve(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := &Clock{now: func() time.Time { return now }}
+
+	assert.Equal(t, now, c.read())
+	assert.Equal(t, now, c.reserve(c.read(), 3))
+	assert.Equal(t, now.Add(3*time.Nanosecond), c.reserve(c.read(), 1))
+	assert.Equal(t, now.Add(4*time.Nanosecond), c.reserve(now.Add(-time.Second), 0))
+	assert.Equal(t, now.Add(4*time.Nanosecond), c.reserve(now.Add(-time.Second), 1))
+	now = now.Add(time.Minute)
+	assert.Equal(t, now, c.reserve(c.read(), 2))
+}
@@ -0,0 +1,36 @@
+package events
+
+import (
+	"bosch-data-exporter/internal/devices"
+	"context"
+	"time"
+)
+
+const DeviceStatusID = "DeviceStatus"
+
+// ExportDeviceStatus wraps a device poll, so every successful poll exports a
+// DeviceStatus event with the availability of each device. The event times
+// come from the clock of the event polling.
+func ExportDeviceStatus(
+	get func(context.Context) ([]*devices.Device, error),
+	exporter exporter,
+	clock *Clock,
+) func(context.Context) ([]*devices.Device, error) {
+	return func(ctx context.Context) ([]*devices.Device, error) {
+		result, err := get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		first := clock.reserve(clock.read(), len(result))
+		for i, d := range result {
+			exporter.Export(&Event{
+				ID:     DeviceStatusID,
+				Type:   DeviceStatusID,
+				Device: d,
+				State:  map[string]interface{}{"status": d.Status},
+				Time:   first.Add(time.Duration(i) * time.Nanosecond),
+			})
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"bosch-data-exporter/internal/devices"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportDeviceStatus(t *testing.T) {
+	dev := &devices.Device{ID: "hdm:HomeMaticIP:1", Name: "Thermostat", Status: "UNAVAILABLE"}
+	other := &devices.Device{ID: "hdm:HomeMaticIP:2", Name: "Plug", Status: "AVAILABLE"}
+	exported := make([]*Event, 0)
+	exporter := mockExporter{func(event *Event) { exported = append(exported, event) }}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := NewClock(func() time.Time { return now })
+	// a poll event received at the same time
+	clock.reserve(now, 1)
+
+	get := ExportDeviceStatus(func(context.Context) ([]*devices.Device, error) {
+		return []*devices.Device{dev, other}, nil
+	}, exporter, clock)
+	got, err := get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []*devices.Device{dev, other}, got)
+	assert.Equal(t, []*Event{
+		{
+			ID:     DeviceStatusID,
+			Type:   DeviceStatusID,
+			Device: dev,
+			State:  map[string]interface{}{"status": "UNAVAILABLE"},
+			Time:   now.Add(time.Nanosecond),
+		},
+		{
+			ID:     DeviceStatusID,
+			Type:   DeviceStatusID,
+			Device: other,
+			State:  map[string]interface{}{"status": "AVAILABLE"},
+			Time:   now.Add(2 * time.Nanosecond),
+		},
+	}, exported, "the times follow the poll events of the same clock")
+
+	failing := ExportDeviceStatus(func(context.Context) ([]*devices.Device, error) {
+		return nil, errors.New("test")
+	}, exporter, clock)
+	_, err = failing(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, exported, 2)
+}
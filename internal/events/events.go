@@ -6,8 +6,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -19,11 +23,16 @@ type httpClient interface {
 }
 
 type devicePolling interface {
-	Get() []*devices.Device
+	Get(ctx context.Context) []*devices.Device
 }
 
 type pollID interface {
-	Get() string
+	Get(ctx context.Context) string
+	Invalidate()
+}
+
+type unsubscriber interface {
+	Unsubscribe(ctx context.Context, pollID string) error
 }
 
 type exporter interface {
@@ -48,6 +57,14 @@ type pollResponseResult struct {
 	ID       string                 `json:"id"`
 	State    map[string]interface{} `json:"state"`
 	DeviceID string                 `json:"deviceId"`
+	Faults   *pollResponseFaults    `json:"faults"`
+}
+
+type pollResponseFaults struct {
+	Entries []struct {
+		Type     string `json:"type"`
+		Category string `json:"category"`
+	} `json:"entries"`
 }
 
 type pollResponseError struct {
@@ -55,36 +72,84 @@ type pollResponseError struct {
 	Message string `json:"message"`
 }
 
+const (
+	invalidSubscriptionCode = -32001
+	minBackoff              = time.Second
+	maxBackoff              = time.Minute
+	unsubscribeTimeout      = 5 * time.Second
+)
+
+var (
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+	ErrEmptyResponse    = errors.New("poll returned empty response")
+)
+
+type RPCError struct {
+	Code    int
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("poll returned error %d: %s", e.Code, e.Message)
+}
+
+func (e *RPCError) InvalidSubscription() bool {
+	return e.Code == invalidSubscriptionCode ||
+		strings.Contains(strings.ToLower(e.Message), "subscription")
+}
+
 type Event struct {
-	ID     string
-	Type   string
-	Device *devices.Device
-	State  map[string]interface{}
+	ID        string
+	Type      string
+	Device    *devices.Device
+	State     map[string]interface{}
+	Faults    []string
+	Heartbeat bool
+	// Time is when the event was received from the SHC. It is unique and
+	// increasing for all events taken from the same Clock.
+	Time time.Time
 }
 
 type SmartHomeEventPolling struct {
 	devices         devicePolling
 	pollID          pollID
+	subscriptions   unsubscriber
 	client          httpClient
 	exporter        exporter
 	baseURL         string
+	minBackoff      time.Duration
+	maxBackoff      time.Duration
 	reqDurationHist prometheus.Histogram
 	eventCountHist  prometheus.Histogram
+	reconnectCount  *prometheus.CounterVec
+	pollErrorCount  *prometheus.CounterVec
+	eventCount      *prometheus.CounterVec
+	unresolvedCount prometheus.Counter
+	lastPoll        atomic.Int64
+	running         atomic.Bool
+	status          pollStatus
+	clock           *Clock
 }
 
 func NewSmartHomeEventPolling(
 	client httpClient,
 	devicePolling devicePolling,
 	pollID pollID,
+	subscriptions unsubscriber,
 	exporter exporter,
+	clock *Clock,
 	config *conf.Config,
 ) *SmartHomeEventPolling {
-	return &SmartHomeEventPolling{
-		client:   client,
-		devices:  devicePolling,
-		pollID:   pollID,
-		exporter: exporter,
-		baseURL:  config.BoschConfig.BaseURL,
+	s := &SmartHomeEventPolling{
+		clock:         clock,
+		client:        client,
+		devices:       devicePolling,
+		pollID:        pollID,
+		subscriptions: subscriptions,
+		exporter:      exporter,
+		baseURL:       config.BoschConfig.BaseURL,
+		minBackoff:    minBackoff,
+		maxBackoff:    maxBackoff,
 		reqDurationHist: promauto.NewHistogram(prometheus.HistogramOpts{
 			Name: "bosch_event_poll_duration",
 			Help: "Duration of the GET Events long poll call",
@@ -93,28 +158,151 @@ func NewSmartHomeEventPolling(
 			Name: "bosch_event_count",
 			Help: "Number of events returned by a long poll",
 		}),
+		reconnectCount: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "bosch_event_poll_reconnects_total",
+			Help: "Number of poll subscriptions renewed after a failed long poll",
+		}, []string{"reason"}),
+		pollErrorCount: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "bosch_event_poll_errors_total",
+			Help: "Number of failed long polls by kind of error",
+		}, []string{"kind"}),
+		eventCount: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "bosch_events_total",
+			Help: "Number of events received by service and device",
+		}, []string{"service", "device"}),
+		unresolvedCount: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "bosch_unresolved_device_events_total",
+			Help: "Number of events of devices that are not known and exported with the default device",
+		}),
 	}
+	s.lastPoll.Store(time.Now().UnixNano())
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bosch_event_last_poll_age_seconds",
+		Help: "Seconds since the last successful long poll",
+	}, func() float64 {
+		return time.Since(time.Unix(0, s.lastPoll.Load())).Seconds()
+	})
+	return s
 }
 
-func (s *SmartHomeEventPolling) Start() {
-	var err error
-	for err == nil {
-		var events []*Event
-		events, err = s.Get()
-		s.eventCountHist.Observe(float64(len(events)))
-		for _, e := range events {
-			if e != nil {
-				s.exporter.Export(e)
+func (s *SmartHomeEventPolling) Start(ctx context.Context) {
+	s.running.Store(true)
+	defer s.running.Store(false)
+	failures := 0
+	needsSnapshot := true
+	subscription := ""
+	for ctx.Err() == nil {
+		// A new subscription, e.g. renewed by the pollID cache, does not
+		// return the changes made before it was created.
+		if id := s.pollID.Get(ctx); id != subscription {
+			subscription = id
+			needsSnapshot = true
+		}
+		if needsSnapshot {
+			needsSnapshot = !s.exportSnapshot(ctx)
+		}
+		events, err := s.Get(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		s.status.record(err)
+		if err != nil {
+			failures++
+			needsSnapshot = true
+			s.handleError(ctx, err, subscription)
+			backoff := s.backoff(failures)
+			log.Info().
+				Int("failures", failures).
+				Dur("backoff", backoff).
+				Msg("Waiting before next poll")
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
 			}
+			continue
+		}
+		failures = 0
+		s.lastPoll.Store(time.Now().UnixNano())
+		s.eventCountHist.Observe(float64(len(events)))
+		s.export(events)
+	}
+	log.Info().Msg("Event polling stopped")
+}
+
+func (s *SmartHomeEventPolling) export(events []*Event) {
+	for _, e := range events {
+		if e != nil {
+			s.exporter.Export(e)
 		}
 	}
-	log.Err(err).Msg("Error while polling data")
 }
 
-func (s *SmartHomeEventPolling) Get() ([]*Event, error) {
+// handleError renews the poll subscription. A subscription that is still
+// valid on the controller is removed, otherwise it stays until it expires.
+func (s *SmartHomeEventPolling) handleError(ctx context.Context, err error, pollID string) {
+	reason := "transport"
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		reason = "rpc"
+		if rpcErr.InvalidSubscription() {
+			reason = "subscription"
+		}
+	}
+	kind := errorKind(err)
+	log.Err(err).
+		Str("reason", reason).
+		Str("kind", kind).
+		Msg("Error while polling data. Renewing poll subscription")
+	s.pollID.Invalidate()
+	if reason != "subscription" && s.subscriptions != nil {
+		unsubscribeCtx, cancel := context.WithTimeout(ctx, unsubscribeTimeout)
+		if e := s.subscriptions.Unsubscribe(unsubscribeCtx, pollID); e != nil {
+			log.Warn().Err(e).Str("pollID", pollID).Msg("Could not remove poll subscription")
+		}
+		cancel()
+	}
+	s.reconnectCount.WithLabelValues(reason).Inc()
+	s.pollErrorCount.WithLabelValues(kind).Inc()
+}
+
+// errorKind classifies poll errors for the bosch_event_poll_errors_total
+// metric.
+func errorKind(err error) string {
+	var rpcErr *RPCError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &rpcErr) && rpcErr.InvalidSubscription():
+		return "subscription"
+	case errors.As(err, &rpcErr):
+		return "rpc"
+	case errors.Is(err, ErrUnexpectedStatus):
+		return "status"
+	case errors.Is(err, ErrEmptyResponse):
+		return "empty"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return "decode"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+	return "transport"
+}
+
+func (s *SmartHomeEventPolling) backoff(failures int) time.Duration {
+	backoff := s.minBackoff
+	for i := 1; i < failures && backoff < s.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.maxBackoff {
+		return s.maxBackoff
+	}
+	return backoff
+}
+
+func (s *SmartHomeEventPolling) Get(ctx context.Context) ([]*Event, error) {
 	timer := prometheus.NewTimer(s.reqDurationHist)
 	defer timer.ObserveDuration()
-	pollID := s.pollID.Get()
+	pollID := s.pollID.Get(ctx)
 	log.Debug().
 		Str("pollID", pollID).
 		Msg("Polling for changes")
@@ -131,7 +319,7 @@ func (s *SmartHomeEventPolling) Get() ([]*Event, error) {
 	}
 	shcPollURL := fmt.Sprintf("%s/remote/json-rpc", s.baseURL)
 	req, err := http.NewRequestWithContext(
-		context.Background(),
+		ctx,
 		http.MethodPost,
 		shcPollURL,
 		bytes.NewReader(requestBodyBytes),
@@ -144,6 +332,7 @@ func (s *SmartHomeEventPolling) Get() ([]*Event, error) {
 	if err != nil {
 		return nil, err
 	}
+	received := s.clock.read()
 	defer func() {
 		e := resp.Body.Close()
 		if e != nil {
@@ -162,7 +351,7 @@ func (s *SmartHomeEventPolling) Get() ([]*Event, error) {
 		Msg("Got poll response")
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("response status of get geive call is not %d, but %d", http.StatusOK, resp.StatusCode)
+		return nil, fmt.Errorf("%w: poll call returned %d instead of %d", ErrUnexpectedStatus, resp.StatusCode, http.StatusOK)
 	}
 
 	var jsonBody []pollResponse
@@ -170,42 +359,65 @@ func (s *SmartHomeEventPolling) Get() ([]*Event, error) {
 	if e := json.Unmarshal(body, &jsonBody); e != nil {
 		return nil, e
 	}
+	if len(jsonBody) == 0 {
+		return nil, ErrEmptyResponse
+	}
 	shcBody := jsonBody[0]
 
-	if shcBody.Error.Message != "" {
-		return nil, fmt.Errorf("poll returned error: %s", jsonBody[0].Error.Message)
-	}
-	events := make([]*Event, 0)
-
-	for i := range shcBody.Result {
-		event := &shcBody.Result[i]
-		log.Debug().
-			Str("deviceID", event.DeviceID).
-			Str("id", event.ID).
-			Str("path", event.Path).
-			Str("type", event.Type).
-			Interface("state", event.State).
-			Msg("poll result")
-		device := s.getDevice(event.DeviceID)
-		if device == nil {
-			device = devices.DefaultDevice()
+	if shcBody.Error.Message != "" || shcBody.Error.Code != 0 {
+		return nil, &RPCError{
+			Code:    shcBody.Error.Code,
+			Message: shcBody.Error.Message,
 		}
-		events = append(
-			events,
-			&Event{
-				ID:     event.ID,
-				Type:   event.Type,
-				Device: device,
-				State:  event.State,
-			},
-		)
 	}
+	return s.toEvents(ctx, shcBody.Result, received), nil
+}
+
+// toEvents converts the results of one response. All events get the receive
+// time of the response, increased by a nanosecond per event to keep the times
+// unique.
+func (s *SmartHomeEventPolling) toEvents(ctx context.Context, results []pollResponseResult, received time.Time) []*Event {
+	first := s.clock.reserve(received, len(results))
+	events := make([]*Event, 0, len(results))
+	for i := range results {
+		events = append(events, s.toEvent(ctx, &results[i], first.Add(time.Duration(i)*time.Nanosecond)))
+	}
+
+	return events
+}
 
-	return events, nil
+func (s *SmartHomeEventPolling) toEvent(ctx context.Context, event *pollResponseResult, received time.Time) *Event {
+	log.Debug().
+		Str("deviceID", event.DeviceID).
+		Str("id", event.ID).
+		Str("path", event.Path).
+		Str("type", event.Type).
+		Interface("state", event.State).
+		Msg("poll result")
+	device := s.getDevice(ctx, event.DeviceID)
+	if device == nil {
+		device = devices.DefaultDevice()
+		s.unresolvedCount.Inc()
+	}
+	s.eventCount.WithLabelValues(event.ID, device.Name).Inc()
+	var faults []string
+	if event.Faults != nil {
+		for _, f := range event.Faults.Entries {
+			faults = append(faults, f.Type)
+		}
+	}
+	return &Event{
+		ID:     event.ID,
+		Type:   event.Type,
+		Device: device,
+		State:  event.State,
+		Faults: faults,
+		Time:   received,
+	}
 }
 
-func (s *SmartHomeEventPolling) getDevice(id string) *devices.Device {
-	for _, d := range s.devices.Get() {
+func (s *SmartHomeEventPolling) getDevice(ctx context.Context, id string) *devices.Device {
+	for _, d := range s.devices.Get(ctx) {
 		if d.ID == id {
 			return d
 		}
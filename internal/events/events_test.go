@@ -2,12 +2,18 @@ package events
 
 import (
 	"bosch-data-exporter/internal/devices"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,18 +21,31 @@ type mockDevices struct {
 	mockGet func() []*devices.Device
 }
 
-func (m *mockDevices) Get() []*devices.Device {
+func (m *mockDevices) Get(context.Context) []*devices.Device {
 	return m.mockGet()
 }
 
 type mockPollID struct {
-	mockGet func() string
+	mockGet        func() string
+	mockInvalidate func()
 }
 
-func (m *mockPollID) Get() string {
+func (m *mockPollID) Get(context.Context) string {
 	return m.mockGet()
 }
 
+func (m *mockPollID) Invalidate() {
+	m.mockInvalidate()
+}
+
+type mockUnsubscriber struct {
+	mockUnsubscribe func(pollID string) error
+}
+
+func (m *mockUnsubscriber) Unsubscribe(_ context.Context, pollID string) error {
+	return m.mockUnsubscribe(pollID)
+}
+
 type mockClient struct {
 	mockDo func(*http.Request) (*http.Response, error)
 }
@@ -43,6 +62,19 @@ func (m mockExporter) Export(event *Event) {
 	m.mockExport(event)
 }
 
+// withTestMetrics sets unregistered metrics, so tests can create pollers
+// without the constructor.
+func withTestMetrics(s *SmartHomeEventPolling) *SmartHomeEventPolling {
+	s.reconnectCount = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "reconnects"}, []string{"reason"})
+	s.pollErrorCount = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "errors"}, []string{"kind"})
+	s.eventCount = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "events"}, []string{"service", "device"})
+	s.unresolvedCount = prometheus.NewCounter(prometheus.CounterOpts{Name: "unresolved"})
+	if s.clock == nil {
+		s.clock = NewClock(nil)
+	}
+	return s
+}
+
 func TestSmartHomeEventPolling_Get(t *testing.T) {
 	dev0 := &devices.Device{
 		Type: "roomClimateControl",
@@ -50,6 +82,7 @@ func TestSmartHomeEventPolling_Get(t *testing.T) {
 		Name: "roomClimateControl",
 		Room: nil,
 	}
+	receiveTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
 	type fields struct {
 		devices  []*devices.Device
 		pollID   string
@@ -81,6 +114,32 @@ func TestSmartHomeEventPolling_Get(t *testing.T) {
 			want:    nil,
 			wantErr: assert.Error,
 		},
+		{
+			name: "invalid subscription",
+			fields: fields{
+				devices: make([]*devices.Device, 0),
+				pollID:  "poll-id",
+				client: &mockClient{
+					mockDo: func(request *http.Request) (*http.Response, error) {
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Body: io.NopCloser(strings.NewReader("[{\"jsonrpc\":\"2.0\"," +
+								"\"error\":{\"code\":-32001,\"message\":\"No subscription with id: poll-id\"}}]\n")),
+						}, nil
+					},
+				},
+				exporter: &mockExporter{
+					func(event *Event) {
+						assert.Fail(t, "exporter should not be called")
+					},
+				},
+			},
+			want: nil,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				var rpcErr *RPCError
+				return assert.ErrorAs(t, err, &rpcErr) && assert.True(t, rpcErr.InvalidSubscription())
+			},
+		},
 		{
 			name: "no results",
 			fields: fields{
@@ -120,6 +179,12 @@ func TestSmartHomeEventPolling_Get(t *testing.T) {
 								"\"id\":\"TemperatureLevel\"," +
 								"\"state\":{\"@type\":\"temperatureLevelState\",\"temperature\":25}," +
 								"\"deviceId\":\"roomClimateControl_hz_5\"" +
+								"},{" +
+								"\"path\":\"/devices/roomClimateControl_hz_5/services/TemperatureLevel\"," +
+								"\"@type\":\"DeviceServiceData\"," +
+								"\"id\":\"TemperatureLevel\"," +
+								"\"state\":{\"@type\":\"temperatureLevelState\",\"temperature\":25.5}," +
+								"\"deviceId\":\"roomClimateControl_hz_5\"" +
 								"}],\"jsonrpc\":\"2.0\"}]\n",
 							)),
 						}, nil
@@ -137,6 +202,14 @@ func TestSmartHomeEventPolling_Get(t *testing.T) {
 					Type:   "DeviceServiceData",
 					Device: dev0,
 					State:  map[string]interface{}{"@type": "temperatureLevelState", "temperature": float64(25)},
+					Time:   receiveTime,
+				},
+				{
+					ID:     "TemperatureLevel",
+					Type:   "DeviceServiceData",
+					Device: dev0,
+					State:  map[string]interface{}{"@type": "temperatureLevelState", "temperature": 25.5},
+					Time:   receiveTime.Add(time.Nanosecond),
 				},
 			},
 			wantErr: assert.NoError,
@@ -144,16 +217,144 @@ func TestSmartHomeEventPolling_Get(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &SmartHomeEventPolling{
-				devices:  &mockDevices{func() []*devices.Device { return tt.fields.devices }},
-				pollID:   &mockPollID{func() string { return tt.fields.pollID }},
+			now := receiveTime
+			s := withTestMetrics(&SmartHomeEventPolling{
+				devices: &mockDevices{func() []*devices.Device {
+					// a slow device lookup must not change the receive time
+					now = now.Add(time.Second)
+					return tt.fields.devices
+				}},
+				pollID:   &mockPollID{mockGet: func() string { return tt.fields.pollID }},
 				client:   tt.fields.client,
 				baseURL:  "http://localhost:8080",
 				exporter: tt.fields.exporter,
-			}
-			got, err := s.Get()
+				clock:    &Clock{now: func() time.Time { return now }},
+			})
+			got, err := s.Get(context.Background())
 			tt.wantErr(t, err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
+
+func TestSmartHomeEventPolling_handleError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		reason string
+		kind   string
+		// a subscription the controller rejects is gone already
+		unsubscribed bool
+	}{
+		{
+			name:         "transport",
+			err:          errors.New("connection refused"),
+			reason:       "transport",
+			kind:         "transport",
+			unsubscribed: true,
+		},
+		{
+			name:   "invalid subscription",
+			err:    &RPCError{Code: -32001, Message: "No subscription with id: poll-id"},
+			reason: "subscription",
+			kind:   "subscription",
+		},
+		{
+			name:         "other rpc error",
+			err:          fmt.Errorf("wrapped: %w", &RPCError{Code: -32600, Message: "Invalid Request"}),
+			reason:       "rpc",
+			kind:         "rpc",
+			unsubscribed: true,
+		},
+		{
+			name:         "status",
+			err:          fmt.Errorf("%w: 503", ErrUnexpectedStatus),
+			reason:       "transport",
+			kind:         "status",
+			unsubscribed: true,
+		},
+		{
+			name:         "decode",
+			err:          json.Unmarshal([]byte("{"), &[]pollResponse{}),
+			reason:       "transport",
+			kind:         "decode",
+			unsubscribed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalidated := false
+			unsubscribed := make([]string, 0)
+			s := withTestMetrics(&SmartHomeEventPolling{
+				pollID: &mockPollID{mockInvalidate: func() { invalidated = true }},
+				subscriptions: &mockUnsubscriber{func(pollID string) error {
+					unsubscribed = append(unsubscribed, pollID)
+					return errors.New("connection refused")
+				}},
+			})
+			s.handleError(context.Background(), tt.err, "poll-id")
+			assert.True(t, invalidated)
+			if tt.unsubscribed {
+				assert.Equal(t, []string{"poll-id"}, unsubscribed)
+			} else {
+				assert.Empty(t, unsubscribed)
+			}
+			assert.Equal(t, float64(1), testutil.ToFloat64(s.reconnectCount.WithLabelValues(tt.reason)))
+			assert.Equal(t, float64(1), testutil.ToFloat64(s.pollErrorCount.WithLabelValues(tt.kind)))
+		})
+	}
+}
+
+func TestSmartHomeEventPolling_backoff(t *testing.T) {
+	s := &SmartHomeEventPolling{
+		minBackoff: time.Second,
+		maxBackoff: 10 * time.Second,
+	}
+	assert.Equal(t, time.Second, s.backoff(1))
+	assert.Equal(t, 2*time.Second, s.backoff(2))
+	assert.Equal(t, 8*time.Second, s.backoff(4))
+	assert.Equal(t, 10*time.Second, s.backoff(5))
+	assert.Equal(t, 10*time.Second, s.backoff(100))
+}
+
+func TestSmartHomeEventPolling_Start_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &SmartHomeEventPolling{
+		devices: &mockDevices{func() []*devices.Device { return nil }},
+		pollID:  &mockPollID{mockGet: func() string { return "poll-id" }},
+		client: &mockClient{
+			mockDo: func(request *http.Request) (*http.Response, error) {
+				if request.URL.Path == "/smarthome/devices/services" {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader("[]")),
+					}, nil
+				}
+				cancel()
+				<-request.Context().Done()
+				return nil, request.Context().Err()
+			},
+		},
+		baseURL: "http://localhost:8080",
+		clock:   NewClock(nil),
+		reqDurationHist: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "duration",
+		}),
+		exporter: &mockExporter{
+			func(event *Event) {
+				assert.Fail(t, "exporter should not be called")
+			},
+		},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Start(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "Start did not return after cancel")
+	}
+}
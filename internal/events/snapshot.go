@@ -0,0 +1,77 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const batteryLevelID = "BatteryLevel"
+
+// exportSnapshot exports the current state of every device service. Start
+// creates the poll subscription first, so no change between both calls is lost.
+func (s *SmartHomeEventPolling) exportSnapshot(ctx context.Context) bool {
+	events, err := s.Snapshot(ctx)
+	if err != nil {
+		log.Err(err).Msg("Error loading device service snapshot")
+		return false
+	}
+	s.status.record(nil)
+	log.Info().Int("number", len(events)).Msg("Exporting device service snapshot")
+	s.export(events)
+	return true
+}
+
+func (s *SmartHomeEventPolling) Snapshot(ctx context.Context) ([]*Event, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/smarthome/devices/services", s.baseURL),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().
+		Str("url", req.URL.Path).
+		Msg("Getting device services...")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	received := s.clock.read()
+	defer func() {
+		e := resp.Body.Close()
+		if e != nil {
+			log.Err(e).Msg("Error closing response body")
+		}
+	}()
+	buf := &bytes.Buffer{}
+	if _, e := buf.ReadFrom(resp.Body); e != nil {
+		return nil, e
+	}
+	body := buf.Bytes()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response status of get services call is not %d, but %d", http.StatusOK, resp.StatusCode)
+	}
+	var jsonBody []pollResponseResult
+
+	if e := json.Unmarshal(body, &jsonBody); e != nil {
+		return nil, e
+	}
+
+	results := make([]pollResponseResult, 0, len(jsonBody))
+	for i := range jsonBody {
+		// a BatteryLevel without faults has no state either, it means the
+		// battery is ok
+		if jsonBody[i].State != nil || jsonBody[i].Faults != nil || jsonBody[i].ID == batteryLevelID {
+			results = append(results, jsonBody[i])
+		}
+	}
+	return s.toEvents(ctx, results, received), nil
+}
@@ -0,0 +1,188 @@
+package events
+
+import (
+	"bosch-data-exporter/internal/devices"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const servicesBody = "[" +
+	"{" +
+	"\"@type\":\"DeviceServiceData\"," +
+	"\"id\":\"TemperatureLevel\"," +
+	"\"deviceId\":\"roomClimateControl_hz_5\"," +
+	"\"state\":{\"@type\":\"temperatureLevelState\",\"temperature\":21.5}," +
+	"\"path\":\"/devices/roomClimateControl_hz_5/services/TemperatureLevel\"" +
+	"}," +
+	"{" +
+	"\"@type\":\"DeviceServiceData\"," +
+	"\"id\":\"BatteryLevel\"," +
+	"\"deviceId\":\"roomClimateControl_hz_5\"," +
+	"\"faults\":{\"entries\":[{\"type\":\"LOW_BATTERY\",\"category\":\"WARNING\"}]}," +
+	"\"path\":\"/devices/roomClimateControl_hz_5/services/BatteryLevel\"" +
+	"}," +
+	"{" +
+	"\"@type\":\"DeviceServiceData\"," +
+	"\"id\":\"BatteryLevel\"," +
+	"\"deviceId\":\"hdm:HomeMaticIP:3014F711A000005D58595588\"," +
+	"\"path\":\"/devices/hdm:HomeMaticIP:3014F711A000005D58595588/services/BatteryLevel\"" +
+	"}," +
+	"{" +
+	"\"@type\":\"DeviceServiceData\"," +
+	"\"id\":\"Thermostat\"," +
+	"\"deviceId\":\"hdm:HomeMaticIP:3014F711A000005D58595588\"," +
+	"\"path\":\"/devices/hdm:HomeMaticIP:3014F711A000005D58595588/services/Thermostat\"" +
+	"}," +
+	"{" +
+	"\"@type\":\"DeviceServiceData\"," +
+	"\"id\":\"ShutterContact\"," +
+	"\"deviceId\":\"unknown\"," +
+	"\"state\":{\"@type\":\"shutterContactState\",\"value\":\"CLOSED\"}," +
+	"\"path\":\"/devices/unknown/services/ShutterContact\"" +
+	"}]"
+
+func TestSmartHomeEventPolling_Snapshot(t *testing.T) {
+	dev0 := &devices.Device{
+		Type: "device",
+		ID:   "roomClimateControl_hz_5",
+		Name: "roomClimateControl",
+	}
+	dev1 := &devices.Device{
+		Type: "device",
+		ID:   "hdm:HomeMaticIP:3014F711A000005D58595588",
+		Name: "Thermostat",
+	}
+	receiveTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		err     error
+		want    []*Event
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "services",
+			body:   servicesBody,
+			status: http.StatusOK,
+			want: []*Event{
+				{
+					ID:     "TemperatureLevel",
+					Type:   "DeviceServiceData",
+					Device: dev0,
+					State:  map[string]interface{}{"@type": "temperatureLevelState", "temperature": 21.5},
+					Time:   receiveTime,
+				},
+				{
+					ID:     "BatteryLevel",
+					Type:   "DeviceServiceData",
+					Device: dev0,
+					Faults: []string{"LOW_BATTERY"},
+					Time:   receiveTime.Add(time.Nanosecond),
+				},
+				{
+					// the battery is ok, the influx exporter writes battery=0
+					ID:     "BatteryLevel",
+					Type:   "DeviceServiceData",
+					Device: dev1,
+					Time:   receiveTime.Add(2 * time.Nanosecond),
+				},
+				{
+					ID:     "ShutterContact",
+					Type:   "DeviceServiceData",
+					Device: devices.DefaultDevice(),
+					State:  map[string]interface{}{"@type": "shutterContactState", "value": "CLOSED"},
+					Time:   receiveTime.Add(3 * time.Nanosecond),
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "status error",
+			body:    "",
+			status:  http.StatusServiceUnavailable,
+			want:    nil,
+			wantErr: assert.Error,
+		},
+		{
+			name:    "http error",
+			err:     errors.New("test"),
+			want:    nil,
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := withTestMetrics(&SmartHomeEventPolling{
+				devices: &mockDevices{func() []*devices.Device { return []*devices.Device{dev0, dev1} }},
+				client: &mockClient{
+					mockDo: func(request *http.Request) (*http.Response, error) {
+						assert.Equal(t, "http://localhost:8080/smarthome/devices/services", request.URL.String())
+						if tt.err != nil {
+							return nil, tt.err
+						}
+						return &http.Response{
+							StatusCode: tt.status,
+							Body:       io.NopCloser(strings.NewReader(tt.body)),
+						}, nil
+					},
+				},
+				baseURL: "http://localhost:8080",
+				clock:   &Clock{now: func() time.Time { return receiveTime }},
+			})
+			got, err := s.Snapshot(context.Background())
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.want, got)
+			if tt.want != nil {
+				assert.Equal(t, float64(1), testutil.ToFloat64(s.unresolvedCount))
+				assert.Equal(t, float64(1), testutil.ToFloat64(s.eventCount.WithLabelValues("TemperatureLevel", "roomClimateControl")))
+			}
+		})
+	}
+}
+
+func TestSmartHomeEventPolling_Start_SnapshotAfterResubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	polls, snapshots := 0, 0
+	s := withTestMetrics(&SmartHomeEventPolling{
+		devices: &mockDevices{func() []*devices.Device { return nil }},
+		pollID: &mockPollID{mockGet: func() string {
+			if polls < 2 {
+				return "first"
+			}
+			return "second"
+		}},
+		client: &mockClient{
+			mockDo: func(request *http.Request) (*http.Response, error) {
+				body := "[]"
+				if request.URL.Path == "/smarthome/devices/services" {
+					snapshots++
+				} else {
+					polls++
+					if polls == 3 {
+						cancel()
+					}
+					body = `[{"result":[],"jsonrpc":"2.0"}]`
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+			},
+		},
+		baseURL:         "http://localhost:8080",
+		reqDurationHist: prometheus.NewHistogram(prometheus.HistogramOpts{Name: "duration"}),
+		eventCountHist:  prometheus.NewHistogram(prometheus.HistogramOpts{Name: "count"}),
+		exporter:        &mockExporter{func(*Event) {}},
+	})
+	s.Start(ctx)
+	assert.Equal(t, 3, polls)
+	assert.Equal(t, 2, snapshots, "a snapshot is exported at start and after the new subscription")
+}
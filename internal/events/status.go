@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxPollAge is the age of the last successful long poll after which the
+// poller is not ready anymore. A long poll takes at most 30 seconds, the
+// backoff after errors at most a minute.
+const maxPollAge = 2 * time.Minute
+
+var (
+	errNotPolled  = errors.New("no poll finished yet")
+	errNotRunning = errors.New("event polling is not running")
+)
+
+// pollStatus keeps the result of the last poll for the readiness checks.
+type pollStatus struct {
+	lock    sync.Mutex
+	lastErr error
+	polled  bool
+}
+
+func (p *pollStatus) record(err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.lastErr = err
+	p.polled = true
+}
+
+func (p *pollStatus) get() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.polled {
+		return errNotPolled
+	}
+	return p.lastErr
+}
+
+// Reachable reports an error if the SHC did not answer the last poll.
+func (s *SmartHomeEventPolling) Reachable(context.Context) error {
+	err := s.status.get()
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return nil
+	}
+	return err
+}
+
+// Subscribed reports an error if the last poll did not use a valid poll
+// subscription.
+func (s *SmartHomeEventPolling) Subscribed(context.Context) error {
+	err := s.status.get()
+	var rpcErr *RPCError
+	if err == nil || errors.As(err, &rpcErr) {
+		return err
+	}
+	return fmt.Errorf("subscription unknown: %w", err)
+}
+
+// Recent reports an error if the poller stopped or the last successful long
+// poll is too old.
+func (s *SmartHomeEventPolling) Recent(context.Context) error {
+	if !s.running.Load() {
+		return errNotRunning
+	}
+	age := time.Since(time.Unix(0, s.lastPoll.Load()))
+	if age > maxPollAge {
+		return fmt.Errorf("last successful poll was %s ago", age.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmartHomeEventPolling_Status(t *testing.T) {
+	tests := []struct {
+		name          string
+		record        bool
+		err           error
+		reachableErr  bool
+		subscribedErr bool
+	}{
+		{
+			name:          "not polled",
+			reachableErr:  true,
+			subscribedErr: true,
+		},
+		{
+			name:   "success",
+			record: true,
+		},
+		{
+			name:          "transport error",
+			record:        true,
+			err:           errors.New("connection refused"),
+			reachableErr:  true,
+			subscribedErr: true,
+		},
+		{
+			name:          "invalid subscription",
+			record:        true,
+			err:           &RPCError{Code: invalidSubscriptionCode, Message: "No subscription"},
+			subscribedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SmartHomeEventPolling{}
+			if tt.record {
+				s.status.record(tt.err)
+			}
+			assert.Equal(t, tt.reachableErr, s.Reachable(context.Background()) != nil)
+			assert.Equal(t, tt.subscribedErr, s.Subscribed(context.Background()) != nil)
+		})
+	}
+}
+
+func TestSmartHomeEventPolling_Recent(t *testing.T) {
+	s := &SmartHomeEventPolling{}
+	s.lastPoll.Store(time.Now().UnixNano())
+	assert.ErrorIs(t, s.Recent(context.Background()), errNotRunning)
+
+	s.running.Store(true)
+	assert.NoError(t, s.Recent(context.Background()))
+
+	s.lastPoll.Store(time.Now().Add(-maxPollAge - time.Second).UnixNano())
+	assert.ErrorContains(t, s.Recent(context.Background()), "last successful poll was")
+}
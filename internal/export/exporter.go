@@ -2,24 +2,43 @@ package export
 
 import (
 	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/devices"
 	"bosch-data-exporter/internal/events"
+	"bosch-data-exporter/internal/metrics"
+	"bosch-data-exporter/internal/wal"
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	influxHttp "github.com/influxdata/influxdb-client-go/v2/api/http"
 	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 )
 
+const closeTimeout = 5 * time.Second
+
 type writeAPI interface {
 	WritePoint(point *write.Point)
+	Flush()
 }
 
 type InfluxExporter struct {
-	writeAPI writeAPI
+	client        influxdb2.Client
+	writeAPI      writeAPI
+	energy        *monotonicCounter
+	energyStore   energyStore
+	parseFailures *prometheus.CounterVec
+	tagNames      []string
+	scheduled     *scheduledSetpoints
+	rawArrays     string
+	buffer        *wal.Log
+	shipper       *bufferShipper
+	cancel        context.CancelFunc
+	running       *sync.WaitGroup
 }
 
 func NewInfluxExporter(config *conf.Config) (*InfluxExporter, error) {
@@ -28,6 +47,22 @@ func NewInfluxExporter(config *conf.Config) (*InfluxExporter, error) {
 		config.InfluxConfig.AuthToken,
 		influxdb2.DefaultOptions(),
 	)
+	ping, err := client.Ping(context.Background())
+	if err == nil && !ping {
+		err = fmt.Errorf("ping did not succeed")
+	}
+	if config.InfluxConfig.Buffer != nil {
+		if err != nil {
+			log.Warn().Err(err).Msg("Influx not reachable. Buffering points until it is available")
+		}
+		return newBufferedInfluxExporter(client, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	failures := newWriteFailureCount()
+	retries := newWriteRetryCount()
 	wAPI := client.WriteAPI(config.InfluxConfig.Org, config.InfluxConfig.Bucket)
 	wAPI.SetWriteFailedCallback(func(batch string, err influxHttp.Error, retryAttempts uint) bool {
 		log.Err(err.Err).
@@ -37,19 +72,145 @@ func NewInfluxExporter(config *conf.Config) (*InfluxExporter, error) {
 			Int("status", err.StatusCode).
 			Uint("retryAfter", err.RetryAfter).
 			Msg("Error writing data to influx")
-		return err.StatusCode != http.StatusUnauthorized
+		failures.Inc()
+		retry := err.StatusCode != http.StatusUnauthorized
+		if retry {
+			retries.Inc()
+		}
+		return retry
 	})
-	ping, err := client.Ping(context.Background())
+
+	e := &InfluxExporter{
+		client:   client,
+		writeAPI: wAPI,
+		energy:   newMonotonicCounter(),
+		energyStore: &influxEnergyStore{
+			queryAPI: client.QueryAPI(config.InfluxConfig.Org),
+			bucket:   config.InfluxConfig.Bucket,
+		},
+		tagNames:      tagNames(config.InfluxConfig),
+		scheduled:     newScheduledSetpoints(config.Location()),
+		parseFailures: newParseFailureCount(),
+		rawArrays:     config.InfluxConfig.RawArrays,
+		running:       &sync.WaitGroup{},
+	}
+	e.start()
+	return e, nil
+}
+
+func newBufferedInfluxExporter(client influxdb2.Client, config *conf.Config) (*InfluxExporter, error) {
+	buffer, err := openBuffer(config.InfluxConfig.Buffer)
 	if err != nil {
 		return nil, err
 	}
-	if !ping {
-		return nil, fmt.Errorf("ping did not succeed")
+	log.Info().
+		Str("dir", config.InfluxConfig.Buffer.Dir).
+		Msg("Buffering influx points on disk")
+	shipper := newBufferShipper(
+		buffer,
+		client.WriteAPIBlocking(config.InfluxConfig.Org, config.InfluxConfig.Bucket),
+		config.InfluxConfig.Buffer,
+	)
+	e := &InfluxExporter{
+		client:   client,
+		writeAPI: &bufferedWriteAPI{log: buffer},
+		energy:   newMonotonicCounter(),
+		energyStore: &influxEnergyStore{
+			queryAPI: client.QueryAPI(config.InfluxConfig.Org),
+			bucket:   config.InfluxConfig.Bucket,
+		},
+		tagNames:      tagNames(config.InfluxConfig),
+		scheduled:     newScheduledSetpoints(config.Location()),
+		parseFailures: newParseFailureCount(),
+		rawArrays:     config.InfluxConfig.RawArrays,
+		buffer:        buffer,
+		shipper:       shipper,
+		running:       &sync.WaitGroup{},
 	}
+	e.start()
+	return e, nil
+}
 
-	return &InfluxExporter{
-		writeAPI: wAPI,
-	}, nil
+// start runs the background tasks of the exporter until Close.
+func (e *InfluxExporter) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.run(ctx, e.exportSchedules)
+	if e.shipper != nil {
+		e.run(ctx, e.shipper.run)
+	}
+}
+
+func (e *InfluxExporter) run(ctx context.Context, task func(ctx context.Context)) {
+	e.running.Add(1)
+	go func() {
+		defer e.running.Done()
+		task(ctx)
+	}()
+}
+
+// exportSchedules writes the scheduled setpoints when a switch point of a
+// schedule is reached, the controller sends no event for it.
+func (e *InfluxExporter) exportSchedules(ctx context.Context) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.exportScheduleChanges(now)
+		}
+	}
+}
+
+func (e *InfluxExporter) exportScheduleChanges(now time.Time) {
+	// the switch points are at full minutes
+	t := now.Truncate(time.Minute)
+	e.scheduled.changed(t, func(device *devices.Device, setpoint float64) {
+		e.writeAPI.WritePoint(influxdb2.NewPoint("room_climate",
+			e.tags(&events.Event{Device: device}),
+			map[string]interface{}{"scheduledSetpointTemperature": setpoint},
+			t,
+		))
+	})
+}
+
+func (e *InfluxExporter) Name() string {
+	return "influx"
+}
+
+func (e *InfluxExporter) Close() error {
+	e.cancel()
+	e.running.Wait()
+	log.Info().Msg("Flushing influx write api")
+	e.writeAPI.Flush()
+	defer e.client.Close()
+	if e.buffer == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if err := e.shipper.ship(ctx); err != nil {
+		log.Warn().Err(err).Msg("Could not write all buffered points. They are written after the next start")
+	}
+	return e.buffer.Close()
+}
+
+// Health pings influx. With a buffer the exporter stays healthy while influx
+// is down, the points are written once it is back.
+func (e *InfluxExporter) Health(ctx context.Context) error {
+	if e.buffer != nil {
+		return nil
+	}
+	ok, err := e.client.Ping(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("ping did not succeed")
+	}
+	return nil
 }
 
 func (e *InfluxExporter) Export(event *events.Event) {
@@ -65,13 +226,81 @@ func (e *InfluxExporter) Export(event *events.Event) {
 }
 
 func (e *InfluxExporter) exportRaw(event *events.Event) {
+	fields := flattenState(event.State, e.rawArrays)
+	if len(fields) == 0 {
+		return
+	}
 	p := influxdb2.NewPoint(fmt.Sprintf("raw_%s", event.ID),
-		map[string]string{
-			"device": event.Device.Name,
-			"room":   event.Device.Room.Name,
-		},
-		event.State,
-		time.Now(),
+		e.tags(event),
+		fields,
+		event.Time,
 	)
 	e.writeAPI.WritePoint(p)
 }
+
+// tags returns the tags of every point of an event as configured in
+// InfluxConfig.Tags.
+func (e *InfluxExporter) tags(event *events.Event) map[string]string {
+	result := make(map[string]string, len(e.tagNames)+1)
+	for _, name := range e.tagNames {
+		addTag(result, name, tagValue(event.Device, name))
+	}
+	if event.Heartbeat {
+		result["heartbeat"] = "true"
+	}
+	return result
+}
+
+func tagNames(config *conf.InfluxConfig) []string {
+	if len(config.Tags) == 0 {
+		return conf.DefaultTags()
+	}
+	return config.Tags
+}
+
+// addTag skips empty values, they are not allowed in line protocol.
+func addTag(tags map[string]string, name string, value string) {
+	if value != "" {
+		tags[name] = value
+	}
+}
+
+func tagValue(device *devices.Device, name string) string {
+	switch name {
+	case "device":
+		return device.Name
+	case "room":
+		return device.Room.Name
+	case "device_id":
+		return device.ID
+	case "room_id":
+		return device.Room.ID
+	case "model":
+		return device.DeviceModel
+	case "serial":
+		return device.Serial
+	case "profile":
+		return device.Profile
+	case "manufacturer":
+		return device.Manufacturer
+	case "root_device":
+		return device.RootDeviceID
+	case "type":
+		return device.Type
+	}
+	return ""
+}
+
+func newWriteFailureCount() prometheus.Counter {
+	return metrics.NewCounter(prometheus.CounterOpts{
+		Name: "bosch_influx_write_failures_total",
+		Help: "Number of failed influx writes",
+	})
+}
+
+func newWriteRetryCount() prometheus.Counter {
+	return metrics.NewCounter(prometheus.CounterOpts{
+		Name: "bosch_influx_write_retries_total",
+		Help: "Number of failed influx writes that are retried",
+	})
+}
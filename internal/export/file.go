@@ -0,0 +1,79 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/events"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type fileRecord struct {
+	Time      time.Time              `json:"time"`
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	DeviceID  string                 `json:"deviceId"`
+	Device    string                 `json:"device"`
+	Room      string                 `json:"room"`
+	State     map[string]interface{} `json:"state"`
+	Heartbeat bool                   `json:"heartbeat,omitempty"`
+}
+
+type FileExporter struct {
+	file    *os.File
+	encoder *json.Encoder
+	lock    *sync.Mutex
+	lastErr error
+}
+
+func NewFileExporter(config *conf.Config) (*FileExporter, error) {
+	file, err := os.OpenFile(config.FileConfig.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{
+		file:    file,
+		encoder: json.NewEncoder(file),
+		lock:    &sync.Mutex{},
+	}, nil
+}
+
+func (e *FileExporter) Name() string {
+	return "file"
+}
+
+func (e *FileExporter) Close() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.file.Close()
+}
+
+// Health returns the error of the last write.
+func (e *FileExporter) Health(context.Context) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.lastErr
+}
+
+func (e *FileExporter) Export(event *events.Event) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	err := e.encoder.Encode(fileRecord{
+		Time:      event.Time,
+		ID:        event.ID,
+		Type:      event.Type,
+		DeviceID:  event.Device.ID,
+		Device:    event.Device.Name,
+		Room:      event.Device.Room.Name,
+		State:     event.State,
+		Heartbeat: event.Heartbeat,
+	})
+	e.lastErr = err
+	if err != nil {
+		log.Err(err).Str("id", event.ID).Msg("Error writing event to file")
+	}
+}
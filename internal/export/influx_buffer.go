@@ -0,0 +1,179 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/metrics"
+	"bosch-data-exporter/internal/wal"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	influxhttp "github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultBufferMaxSizeMB     = 100
+	defaultBufferMaxAgeHours   = 7 * 24
+	defaultBufferFlushInterval = 10
+	bufferSegmentBytes         = 1 << 20
+	bytesPerMB                 = 1 << 20
+)
+
+type blockingWriteAPI interface {
+	WriteRecord(ctx context.Context, line ...string) error
+}
+
+// bufferedWriteAPI writes points to the on-disk buffer instead of influx.
+// The bufferShipper writes the buffered points to influx.
+type bufferedWriteAPI struct {
+	log *wal.Log
+}
+
+func openBuffer(config *conf.BufferConfig) (*wal.Log, error) {
+	maxSizeMB := config.MaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultBufferMaxSizeMB
+	}
+	maxAgeHours := config.MaxAgeHours
+	if maxAgeHours <= 0 {
+		maxAgeHours = defaultBufferMaxAgeHours
+	}
+	return wal.Open(config.Dir, wal.Options{
+		MaxBytes:        int64(maxSizeMB) * bytesPerMB,
+		MaxAge:          time.Duration(maxAgeHours) * time.Hour,
+		MaxSegmentBytes: bufferSegmentBytes,
+	})
+}
+
+func (b *bufferedWriteAPI) WritePoint(point *write.Point) {
+	line := strings.TrimSuffix(write.PointToLineProtocol(point, time.Nanosecond), "\n")
+	if err := b.log.Append(line); err != nil {
+		log.Err(err).Str("point", line).Msg("Error writing point to buffer")
+	}
+}
+
+func (b *bufferedWriteAPI) Flush() {
+	if err := b.log.Seal(); err != nil {
+		log.Err(err).Msg("Error sealing buffer segment")
+	}
+}
+
+type bufferShipper struct {
+	log      *wal.Log
+	writeAPI blockingWriteAPI
+	interval time.Duration
+	failures prometheus.Counter
+	retries  prometheus.Counter
+	rejected prometheus.Counter
+}
+
+func newBufferShipper(log *wal.Log, writeAPI blockingWriteAPI, config *conf.BufferConfig) *bufferShipper {
+	interval := config.FlushInterval
+	if interval <= 0 {
+		interval = defaultBufferFlushInterval
+	}
+	return &bufferShipper{
+		log:      log,
+		writeAPI: writeAPI,
+		interval: time.Duration(interval) * time.Second,
+		failures: newWriteFailureCount(),
+		retries:  newWriteRetryCount(),
+		rejected: metrics.NewCounter(prometheus.CounterOpts{
+			Name: "bosch_influx_rejected_points_total",
+			Help: "Number of buffered points dropped because influx rejected them",
+		}),
+	}
+}
+
+func (s *bufferShipper) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ship(ctx); err != nil {
+				log.Err(err).Msg("Error writing buffered points to influx")
+			}
+		}
+	}
+}
+
+// ship writes all sealed segments to influx in order, oldest first. A segment
+// is only removed after it was written successfully. Points influx rejects are
+// dropped.
+func (s *bufferShipper) ship(ctx context.Context) error {
+	if err := s.log.Seal(); err != nil {
+		return err
+	}
+	for {
+		segment, err := s.log.Next()
+		if errors.Is(err, wal.ErrNoSegment) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if e := s.write(ctx, segment.Name, segment.Lines); e != nil {
+			// the segment stays in the buffer and is written again later
+			s.failures.Inc()
+			s.retries.Inc()
+			return e
+		}
+		log.Debug().
+			Str("segment", segment.Name).
+			Int("points", len(segment.Lines)).
+			Msg("Wrote buffered points to influx")
+		if e := s.log.Remove(segment); e != nil {
+			return e
+		}
+	}
+}
+
+// write writes the lines of a segment. If influx rejects them, they are split
+// until the rejected lines are found, which are dropped, because writing them
+// again fails the same way and blocks all later segments. Lines written before
+// another error are written again with the segment, influx overwrites points
+// with the same series and time.
+func (s *bufferShipper) write(ctx context.Context, segment string, lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	err := s.writeAPI.WriteRecord(ctx, lines...)
+	if err == nil || !rejected(err) {
+		return err
+	}
+	s.failures.Inc()
+	if len(lines) == 1 {
+		log.Err(err).
+			Str("segment", segment).
+			Str("point", lines[0]).
+			Msg("Influx rejected buffered point, dropping it")
+		s.rejected.Inc()
+		return nil
+	}
+	half := len(lines) / 2
+	if e := s.write(ctx, segment, lines[:half]); e != nil {
+		return e
+	}
+	return s.write(ctx, segment, lines[half:])
+}
+
+// rejected returns whether influx refused the points themselves, e.g. invalid
+// line protocol or a field type conflict. Other errors like a missing
+// permission, a missing bucket or a too large request go away once influx is
+// fixed, so the points are kept.
+func rejected(err error) bool {
+	var httpErr *influxhttp.Error
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	return httpErr.StatusCode == http.StatusBadRequest ||
+		httpErr.StatusCode == http.StatusUnprocessableEntity
+}
@@ -0,0 +1,124 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/wal"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	influxhttp "github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockBlockingWriteAPI struct {
+	err     error
+	errs    []error
+	reject  string
+	written []string
+}
+
+func (m *mockBlockingWriteAPI) WriteRecord(_ context.Context, line ...string) error {
+	for _, l := range line {
+		if m.reject != "" && strings.Contains(l, m.reject) {
+			return &influxhttp.Error{StatusCode: http.StatusBadRequest, Code: "invalid", Message: "field type conflict"}
+		}
+	}
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	if m.err != nil {
+		return m.err
+	}
+	m.written = append(m.written, line...)
+	return nil
+}
+
+func TestBufferShipper_Ship(t *testing.T) {
+	config := &conf.BufferConfig{Dir: t.TempDir()}
+	buffer, err := openBuffer(config)
+	require.NoError(t, err)
+	writeAPI := &mockBlockingWriteAPI{err: errors.New("influx down")}
+	shipper := newBufferShipper(buffer, writeAPI, config)
+	buffered := &bufferedWriteAPI{log: buffer}
+
+	failures := testutil.ToFloat64(shipper.failures)
+	first := time.Unix(0, 1000)
+	buffered.WritePoint(write.NewPoint("temperature", map[string]string{"room": "Bad"}, map[string]interface{}{"temperature": 21.5}, first))
+	assert.Error(t, shipper.ship(context.Background()))
+	assert.Equal(t, failures+1, testutil.ToFloat64(shipper.failures))
+
+	buffered.WritePoint(write.NewPoint("temperature", map[string]string{"room": "Bad"}, map[string]interface{}{"temperature": 22.0}, first.Add(time.Second)))
+	writeAPI.err = nil
+	require.NoError(t, shipper.ship(context.Background()))
+
+	assert.Equal(t, []string{
+		"temperature,room=Bad temperature=21.5 1000",
+		"temperature,room=Bad temperature=22 1000001000",
+	}, writeAPI.written)
+	require.NoError(t, shipper.ship(context.Background()))
+	assert.Len(t, writeAPI.written, 2)
+	assert.Equal(t, 10*time.Second, shipper.interval)
+}
+
+func TestBufferShipper_Ship_Rejected(t *testing.T) {
+	config := &conf.BufferConfig{Dir: t.TempDir()}
+	buffer, err := openBuffer(config)
+	require.NoError(t, err)
+	writeAPI := &mockBlockingWriteAPI{
+		errs:   []error{&influxhttp.Error{StatusCode: http.StatusTooManyRequests}},
+		reject: "setpointTemperatureForLevelEco",
+	}
+	shipper := newBufferShipper(buffer, writeAPI, config)
+	buffered := &bufferedWriteAPI{log: buffer}
+
+	rejected := testutil.ToFloat64(shipper.rejected)
+	buffered.WritePoint(write.NewPoint("temperature", map[string]string{"room": "Bad"}, map[string]interface{}{"temperature": 21.5}, time.Unix(0, 1000)))
+	buffered.WritePoint(write.NewPoint("room_climate", map[string]string{"room": "Bad"}, map[string]interface{}{"setpointTemperatureForLevelEco": 17.5}, time.Unix(0, 1000)))
+	buffered.WritePoint(write.NewPoint("temperature", map[string]string{"room": "Bad"}, map[string]interface{}{"temperature": 22.0}, time.Unix(0, 2000)))
+	assert.Error(t, shipper.ship(context.Background()), "too many requests is retried")
+
+	require.NoError(t, shipper.ship(context.Background()))
+	assert.Equal(t, []string{
+		"temperature,room=Bad temperature=21.5 1000",
+		"temperature,room=Bad temperature=22 2000",
+	}, writeAPI.written, "only the rejected point is dropped")
+	assert.Equal(t, rejected+1, testutil.ToFloat64(shipper.rejected))
+	_, err = buffer.Next()
+	assert.ErrorIs(t, err, wal.ErrNoSegment)
+}
+
+func TestBufferShipper_Ship_KeepsSegmentUntilInfluxIsFixed(t *testing.T) {
+	for _, status := range []int{
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusNotFound,
+		http.StatusRequestEntityTooLarge,
+	} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			config := &conf.BufferConfig{Dir: t.TempDir()}
+			buffer, err := openBuffer(config)
+			require.NoError(t, err)
+			writeAPI := &mockBlockingWriteAPI{err: &influxhttp.Error{StatusCode: status}}
+			shipper := newBufferShipper(buffer, writeAPI, config)
+			buffered := &bufferedWriteAPI{log: buffer}
+
+			buffered.WritePoint(write.NewPoint("temperature", map[string]string{"room": "Bad"}, map[string]interface{}{"temperature": 21.5}, time.Unix(0, 1000)))
+			assert.Error(t, shipper.ship(context.Background()))
+
+			writeAPI.err = nil
+			require.NoError(t, shipper.ship(context.Background()))
+			assert.Equal(t, []string{"temperature,room=Bad temperature=21.5 1000"}, writeAPI.written)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/events"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type ShutterControlState struct {
+	Type           string  `json:"@type"`
+	Level          float64 `json:"level"`
+	Calibrated     bool    `json:"calibrated"`
+	OperationState string  `json:"operationState"`
+}
+
+type MultiLevelSwitchState struct {
+	Type  string  `json:"@type"`
+	Level float64 `json:"level"`
+}
+
+type BinarySwitchState struct {
+	Type string `json:"@type"`
+	On   bool   `json:"on"`
+}
+
+func (e *InfluxExporter) exportShutterControl(event *events.Event) {
+	var parsedState ShutterControlState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	fields := map[string]interface{}{
+		"level":      parsedState.Level,
+		"calibrated": boolToInt(parsedState.Calibrated),
+	}
+	if parsedState.OperationState != "" {
+		fields["operationState"] = parsedState.OperationState
+		fields["moving"] = boolToInt(parsedState.OperationState != "STOPPED")
+	}
+	p := influxdb2.NewPoint("shutter_control",
+		e.tags(event),
+		fields,
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportMultiLevelSwitch(event *events.Event) {
+	var parsedState MultiLevelSwitchState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	p := influxdb2.NewPoint("multi_level_switch",
+		e.tags(event),
+		map[string]interface{}{
+			"level": parsedState.Level,
+		},
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportBinarySwitch(event *events.Event) {
+	var parsedState BinarySwitchState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	p := influxdb2.NewPoint("binary_switch",
+		e.tags(event),
+		map[string]interface{}{
+			"on": boolToInt(parsedState.On),
+		},
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
@@ -2,10 +2,13 @@ package export
 
 import (
 	"bosch-data-exporter/internal/events"
+	"bosch-data-exporter/internal/metrics"
+	"bosch-data-exporter/internal/schedule"
 	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/mitchellh/mapstructure"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 )
 
@@ -31,29 +34,34 @@ type ValveTappetState struct {
 }
 
 type ClimateControlState struct {
-	Type            string `json:"@type"`
-	BoostMode       bool   `json:"boostMode"`
-	Low             bool   `json:"low"`
-	OperationMode   string `json:"operationMode"`
-	RoomControlMode string `json:"roomControlMode"`
-	Schedule        struct {
-		Profiles []struct {
-			Day          string `json:"day"`
-			SwitchPoints []struct {
-				StartTimeMinutes int `json:"startTimeMinutes"`
-				Value            struct {
-					Type             string `json:"@type"`
-					TemperatureLevel string `json:"temperatureLevel"`
-				} `json:"value"`
-			} `json:"switchPoints"`
-		} `json:"profiles"`
-	} `json:"schedule"`
-	SetpointTemperature                float64 `json:"setpointTemperature"`
-	SetpointTemperatureForLevelComfort float64 `json:"setpointTemperatureForLevelComfort"`
-	SetpointTemperatureForLevelEco     int     `json:"setpointTemperatureForLevelEco"`
-	SummerMode                         bool    `json:"summerMode"`
-	SupportsBoostMode                  bool    `json:"supportsBoostMode"`
-	VentilationMode                    bool    `json:"ventilationMode"`
+	Type                               string            `json:"@type"`
+	BoostMode                          bool              `json:"boostMode"`
+	Low                                bool              `json:"low"`
+	OperationMode                      string            `json:"operationMode"`
+	RoomControlMode                    string            `json:"roomControlMode"`
+	Schedule                           schedule.Schedule `json:"schedule"`
+	SetpointTemperature                float64           `json:"setpointTemperature"`
+	SetpointTemperatureForLevelComfort float64           `json:"setpointTemperatureForLevelComfort"`
+	SetpointTemperatureForLevelEco     float64           `json:"setpointTemperatureForLevelEco"`
+	SummerMode                         bool              `json:"summerMode"`
+	SupportsBoostMode                  bool              `json:"supportsBoostMode"`
+	VentilationMode                    bool              `json:"ventilationMode"`
+}
+
+// scheduledSetpoint returns the setpoint of the temperature level the schedule
+// has active at the given time in the time zone of the controller.
+func (s *ClimateControlState) scheduledSetpoint(t time.Time, location *time.Location) (float64, bool) {
+	level, ok := s.Schedule.LevelAt(t.In(location))
+	switch {
+	case !ok:
+		return 0, false
+	case level == schedule.LevelComfort:
+		return s.SetpointTemperatureForLevelComfort, true
+	case level == schedule.LevelEco:
+		return s.SetpointTemperatureForLevelEco, true
+	default:
+		return 0, false
+	}
 }
 
 func (e *InfluxExporter) parseAndExport(event *events.Event) {
@@ -68,13 +76,47 @@ func (e *InfluxExporter) parseAndExport(event *events.Event) {
 		e.ExportHumidityLevelState(event)
 	case "ValveTappet":
 		e.ExportValveTappetState(event)
+	case "PowerMeter":
+		e.exportPowerMeter(event)
+	case "PowerSwitch":
+		e.exportPowerSwitch(event)
+	case "AirQualityLevel":
+		e.exportAirQualityLevel(event)
+	case "SmokeDetectorCheck":
+		e.exportSmokeDetectorCheck(event)
+	case "SmokeSensitivity":
+		e.exportSmokeSensitivity(event)
+	case "Alarm":
+		e.exportAlarm(event)
+	case "LatestMotion":
+		e.exportLatestMotion(event)
+	case "WaterLeakageSensor":
+		e.exportWaterLeakageSensor(event)
+	case "WaterLeakageSensorTilt":
+		e.exportWaterLeakageSensorTilt(event)
+	case "ShutterContact2":
+		e.exportShutterContact2(event)
+	case "VibrationSensor":
+		e.exportVibrationSensor(event)
+	case "ShutterControl":
+		e.exportShutterControl(event)
+	case "MultiLevelSwitch":
+		e.exportMultiLevelSwitch(event)
+	case "BinarySwitch":
+		e.exportBinarySwitch(event)
+	case "BatteryLevel":
+		e.exportBatteryLevel(event)
+	case "CommunicationQuality":
+		e.exportCommunicationQuality(event)
+	case events.DeviceStatusID:
+		e.exportDeviceStatus(event)
 	}
 }
 
 func (e *InfluxExporter) exportRoomClimateControl(event *events.Event) {
 	var parsedState ClimateControlState
 
-	if err := parseState(&parsedState, event.State); err != nil {
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
 		log.Err(err).Msg("Error parsing state")
 		return
 	}
@@ -82,7 +124,12 @@ func (e *InfluxExporter) exportRoomClimateControl(event *events.Event) {
 	fields := map[string]interface{}{
 		"setpointTemperature":                parsedState.SetpointTemperature,
 		"setpointTemperatureForLevelComfort": parsedState.SetpointTemperatureForLevelComfort,
-		"setpointTemperatureForLevelEco":     parsedState.SetpointTemperatureForLevelEco,
+		// the field was written as integer before, influx rejects a change of
+		// the field type
+		"setpointTemperatureForLevelEco": int(parsedState.SetpointTemperatureForLevelEco),
+	}
+	if scheduled, ok := e.scheduled.update(event.Device, parsedState, event.Time); ok {
+		fields["scheduledSetpointTemperature"] = scheduled
 	}
 	if parsedState.SummerMode {
 		fields["summerMode"] = 1
@@ -105,12 +152,9 @@ func (e *InfluxExporter) exportRoomClimateControl(event *events.Event) {
 		fields["low"] = 0
 	}
 	p := influxdb2.NewPoint("room_climate",
-		map[string]string{
-			"device": event.Device.Name,
-			"room":   event.Device.Room.Name,
-		},
+		e.tags(event),
 		fields,
-		time.Now(),
+		event.Time,
 	)
 	e.writeAPI.WritePoint(p)
 }
@@ -118,7 +162,7 @@ func (e *InfluxExporter) exportRoomClimateControl(event *events.Event) {
 func (e *InfluxExporter) exportShutterContact(event *events.Event) {
 	var parsedState ShutterContactState
 
-	if err := parseState(&parsedState, event.State); err != nil {
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
 		log.Err(err).Msg("Error parsing state")
 		return
 	}
@@ -130,12 +174,9 @@ func (e *InfluxExporter) exportShutterContact(event *events.Event) {
 		fields["open"] = 0
 	}
 	p := influxdb2.NewPoint("shutter_contact",
-		map[string]string{
-			"device": event.Device.Name,
-			"room":   event.Device.Room.Name,
-		},
+		e.tags(event),
 		fields,
-		time.Now(),
+		event.Time,
 	)
 	e.writeAPI.WritePoint(p)
 }
@@ -143,7 +184,7 @@ func (e *InfluxExporter) exportShutterContact(event *events.Event) {
 func (e *InfluxExporter) ExportTemperatureLevelState(event *events.Event) {
 	var parsedState TemperatureLevelState
 
-	if err := parseState(&parsedState, event.State); err != nil {
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
 		log.Err(err).Msg("Error parsing state")
 		return
 	}
@@ -152,12 +193,9 @@ func (e *InfluxExporter) ExportTemperatureLevelState(event *events.Event) {
 		"temperature": parsedState.Temperature,
 	}
 	p := influxdb2.NewPoint("temperature",
-		map[string]string{
-			"device": event.Device.Name,
-			"room":   event.Device.Room.Name,
-		},
+		e.tags(event),
 		fields,
-		time.Now(),
+		event.Time,
 	)
 	e.writeAPI.WritePoint(p)
 }
@@ -165,7 +203,7 @@ func (e *InfluxExporter) ExportTemperatureLevelState(event *events.Event) {
 func (e *InfluxExporter) ExportValveTappetState(event *events.Event) {
 	var parsedState ValveTappetState
 
-	if err := parseState(&parsedState, event.State); err != nil {
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
 		log.Err(err).Msg("Error parsing state")
 		return
 	}
@@ -174,12 +212,9 @@ func (e *InfluxExporter) ExportValveTappetState(event *events.Event) {
 		"position": parsedState.Position,
 	}
 	p := influxdb2.NewPoint("valve_tappet",
-		map[string]string{
-			"device": event.Device.Name,
-			"room":   event.Device.Room.Name,
-		},
+		e.tags(event),
 		fields,
-		time.Now(),
+		event.Time,
 	)
 	e.writeAPI.WritePoint(p)
 }
@@ -187,7 +222,7 @@ func (e *InfluxExporter) ExportValveTappetState(event *events.Event) {
 func (e *InfluxExporter) ExportHumidityLevelState(event *events.Event) {
 	var parsedState HumidityLevelState
 
-	if err := parseState(&parsedState, event.State); err != nil {
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
 		log.Err(err).Msg("Error parsing state")
 		return
 	}
@@ -196,17 +231,15 @@ func (e *InfluxExporter) ExportHumidityLevelState(event *events.Event) {
 		"humidity": parsedState.Humidity,
 	}
 	p := influxdb2.NewPoint("humidity",
-		map[string]string{
-			"device": event.Device.Name,
-			"room":   event.Device.Room.Name,
-		},
+		e.tags(event),
 		fields,
-		time.Now(),
+		event.Time,
 	)
 	e.writeAPI.WritePoint(p)
 }
 
-func parseState(x interface{}, input map[string]interface{}) error {
+// parseState decodes a service state and counts failures by state type.
+func parseState(x interface{}, input map[string]interface{}, failures *prometheus.CounterVec) error {
 	config := &mapstructure.DecoderConfig{
 		TagName: "json",
 	}
@@ -215,5 +248,17 @@ func parseState(x interface{}, input map[string]interface{}) error {
 	if err != nil {
 		return err
 	}
-	return decoder.Decode(input)
+	if err = decoder.Decode(input); err != nil {
+		stateType, _ := input["@type"].(string)
+		failures.WithLabelValues(stateType).Inc()
+		return err
+	}
+	return nil
+}
+
+func newParseFailureCount() *prometheus.CounterVec {
+	return metrics.NewCounterVec(prometheus.CounterOpts{
+		Name: "bosch_state_parse_failures_total",
+		Help: "Number of service states that could not be parsed by state type",
+	}, []string{"type"})
 }
@@ -0,0 +1,457 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/devices"
+	"bosch-data-exporter/internal/events"
+	"bosch-data-exporter/internal/rooms"
+	"bosch-data-exporter/internal/schedule"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockWriteAPI struct {
+	points []*write.Point
+}
+
+func (m *mockWriteAPI) WritePoint(point *write.Point) {
+	m.points = append(m.points, point)
+}
+
+func (m *mockWriteAPI) Flush() {}
+
+// lines returns the written points as line protocol without timestamps.
+func (m *mockWriteAPI) lines() []string {
+	result := make([]string, 0, len(m.points))
+	for _, p := range m.points {
+		line := write.PointToLineProtocol(p, time.Nanosecond)
+		result = append(result, line[:strings.LastIndex(line, " ")])
+	}
+	return result
+}
+
+func newTestInfluxExporter() (*InfluxExporter, *mockWriteAPI) {
+	writeAPI := &mockWriteAPI{}
+	return &InfluxExporter{
+		writeAPI:      writeAPI,
+		energy:        newMonotonicCounter(),
+		tagNames:      conf.DefaultTags(),
+		scheduled:     newScheduledSetpoints(time.UTC),
+		parseFailures: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "parse_failures"}, []string{"type"}),
+	}, writeAPI
+}
+
+var testTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+const testTags = "device=Plug,room=Kitchen"
+
+func testDevice() *devices.Device {
+	return &devices.Device{
+		ID:          "hdm:ZigBee:70ac08fffe6a1b2c",
+		DeviceModel: "PSM",
+		Serial:      "70AC08FFFE6A1B2C",
+		Name:        "Plug",
+		Room:        &rooms.Room{ID: "hz_1", Name: "Kitchen"},
+	}
+}
+
+func TestInfluxExporter_parseAndExport(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		state  map[string]interface{}
+		faults []string
+		want   []string
+	}{
+		{
+			name: "room climate control",
+			id:   "RoomClimateControl",
+			state: map[string]interface{}{
+				"@type":                              "climateControlState",
+				"setpointTemperature":                float64(23),
+				"setpointTemperatureForLevelComfort": 21.5,
+				"setpointTemperatureForLevelEco":     17.5,
+				"schedule": map[string]interface{}{"profiles": []interface{}{
+					map[string]interface{}{"day": "MONDAY", "switchPoints": []interface{}{
+						map[string]interface{}{
+							"startTimeMinutes": float64(0),
+							"value":            map[string]interface{}{"temperatureLevel": "ECO"},
+						},
+					}},
+				}},
+			},
+			want: []string{"room_climate," + testTags + " boostMode=0i,low=0i,scheduledSetpointTemperature=17.5," +
+				"setpointTemperature=23,setpointTemperatureForLevelComfort=21.5,setpointTemperatureForLevelEco=17i," +
+				"summerMode=0i,ventilationMode=0i"},
+		},
+		{
+			name: "power meter",
+			id:   "PowerMeter",
+			state: map[string]interface{}{
+				"@type":             "powerMeterState",
+				"powerConsumption":  float64(42),
+				"energyConsumption": 1195.5,
+			},
+			want: []string{"power," + testTags + " " +
+				"energyConsumption=1195.5,energyConsumptionTotal=1195.5,powerConsumption=42"},
+		},
+		{
+			name: "power switch",
+			id:   "PowerSwitch",
+			state: map[string]interface{}{
+				"@type":                 "powerSwitchState",
+				"switchState":           "ON",
+				"automaticPowerOffTime": float64(0),
+			},
+			want: []string{"power_switch," + testTags + " automaticPowerOffTime=0i,on=1i"},
+		},
+		{
+			name: "air quality",
+			id:   "AirQualityLevel",
+			state: map[string]interface{}{
+				"@type":             "airQualityLevelState",
+				"combinedRating":    "MEDIUM",
+				"description":       "",
+				"temperature":       float64(23),
+				"temperatureRating": "GOOD",
+				"humidity":          32.5,
+				"humidityRating":    "MEDIUM",
+				"purity":            float64(620),
+				"purityRating":      "UNKNOWN",
+				"comfortZone":       map[string]interface{}{"minTemperature": float64(19)},
+			},
+			want: []string{"air_quality," + testTags + " " +
+				"combinedRating=1i,humidity=32.5,humidityRating=1i,purity=620,temperature=23,temperatureRating=0i"},
+		},
+		{
+			name:  "smoke detector check",
+			id:    "SmokeDetectorCheck",
+			state: map[string]interface{}{"@type": "smokeDetectorCheckState", "value": "SMOKE_TEST_OK"},
+			want: []string{"smoke_detector_check," + testTags + " " +
+				"testFailed=0i,testOk=1i,value=\"SMOKE_TEST_OK\""},
+		},
+		{
+			name:  "smoke sensitivity",
+			id:    "SmokeSensitivity",
+			state: map[string]interface{}{"@type": "smokeSensitivityState", "smokeSensitivity": "HIGH"},
+			want:  []string{"smoke_sensitivity," + testTags + " sensitivity=2i"},
+		},
+		{
+			name: "alarm",
+			id:   "Alarm",
+			state: map[string]interface{}{
+				"@type":     "alarmState",
+				"value":     "PRIMARY_ALARM",
+				"incidents": []interface{}{},
+				"deleted":   false,
+			},
+			want: []string{"alarm," + testTags + " active=1i,level=3i,value=\"PRIMARY_ALARM\""},
+		},
+		{
+			name:  "water leakage",
+			id:    "WaterLeakageSensor",
+			state: map[string]interface{}{"@type": "waterLeakageSensorState", "state": "LEAKAGE_DETECTED"},
+			want:  []string{"water_leakage," + testTags + " leakage=1i,state=\"LEAKAGE_DETECTED\""},
+		},
+		{
+			name: "water leakage tilt",
+			id:   "WaterLeakageSensorTilt",
+			state: map[string]interface{}{
+				"@type":                 "waterLeakageSensorTiltState",
+				"pushNotificationState": "ENABLED",
+				"acousticSignalState":   "DISABLED",
+			},
+			want: []string{"water_leakage_tilt," + testTags + " acousticSignal=0i,pushNotification=1i"},
+		},
+		{
+			name:  "shutter contact 2",
+			id:    "ShutterContact2",
+			state: map[string]interface{}{"@type": "shutterContact2State", "value": "OPEN"},
+			want:  []string{"shutter_contact2," + testTags + " open=1i"},
+		},
+		{
+			name: "vibration",
+			id:   "VibrationSensor",
+			state: map[string]interface{}{
+				"@type":       "vibrationSensorState",
+				"value":       "NO_VIBRATION",
+				"enabled":     true,
+				"sensitivity": "HIGH",
+			},
+			want: []string{"vibration," + testTags + " enabled=1i,sensitivity=\"HIGH\",vibration=0i"},
+		},
+		{
+			name: "shutter control",
+			id:   "ShutterControl",
+			state: map[string]interface{}{
+				"@type":          "shutterControlState",
+				"level":          0.75,
+				"calibrated":     true,
+				"operationState": "MOVING",
+			},
+			want: []string{"shutter_control," + testTags + " " +
+				"calibrated=1i,level=0.75,moving=1i,operationState=\"MOVING\""},
+		},
+		{
+			name:  "multi level switch",
+			id:    "MultiLevelSwitch",
+			state: map[string]interface{}{"@type": "multiLevelSwitchState", "level": float64(50)},
+			want:  []string{"multi_level_switch," + testTags + " level=50"},
+		},
+		{
+			name:  "binary switch",
+			id:    "BinarySwitch",
+			state: map[string]interface{}{"@type": "binarySwitchState", "on": true},
+			want:  []string{"binary_switch," + testTags + " on=1i"},
+		},
+		{
+			name: "battery ok",
+			id:   "BatteryLevel",
+			want: []string{"device_health," + testTags + " battery=0i"},
+		},
+		{
+			name:   "battery critical",
+			id:     "BatteryLevel",
+			faults: []string{"LOW_BATTERY", "CRITICAL_LOW"},
+			want:   []string{"device_health," + testTags + " battery=2i"},
+		},
+		{
+			name:  "communication quality",
+			id:    "CommunicationQuality",
+			state: map[string]interface{}{"@type": "communicationQualityState", "quality": "BAD"},
+			want:  []string{"device_health," + testTags + " communicationQuality=2i"},
+		},
+		{
+			name:  "device status",
+			id:    "DeviceStatus",
+			state: map[string]interface{}{"status": "UNAVAILABLE"},
+			want:  []string{"device_health," + testTags + " available=0i,status=\"UNAVAILABLE\""},
+		},
+		{
+			name:  "invalid state",
+			id:    "PowerSwitch",
+			state: map[string]interface{}{"switchState": 1},
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, writeAPI := newTestInfluxExporter()
+			e.parseAndExport(&events.Event{
+				ID:     tt.id,
+				Device: testDevice(),
+				State:  tt.state,
+				Faults: tt.faults,
+				Time:   testTime,
+			})
+			assert.Equal(t, tt.want, writeAPI.lines())
+			for _, p := range writeAPI.points {
+				assert.Equal(t, testTime, p.Time())
+			}
+		})
+	}
+}
+
+func TestMonotonicCounter_Update(t *testing.T) {
+	c := newMonotonicCounter()
+	assert.Equal(t, float64(100), c.Update("plug", 100))
+	assert.Equal(t, float64(150), c.Update("plug", 150))
+	assert.Equal(t, float64(160), c.Update("plug", 10))
+	assert.Equal(t, float64(170), c.Update("plug", 20))
+	assert.Equal(t, float64(5), c.Update("other", 5))
+}
+
+type mockEnergyStore struct {
+	tag, value  string
+	last, total float64
+	calls       int
+}
+
+func (m *mockEnergyStore) lastEnergy(_ context.Context, tag string, value string) (float64, float64, bool, error) {
+	m.calls++
+	return m.last, m.total, tag == m.tag && value == m.value, nil
+}
+
+func TestInfluxExporter_seedEnergy(t *testing.T) {
+	e, writeAPI := newTestInfluxExporter()
+	store := &mockEnergyStore{tag: "device", value: "Plug", last: 100, total: 250}
+	e.energyStore = store
+	for _, energy := range []float64{120, 10} {
+		e.parseAndExport(&events.Event{
+			ID:     "PowerMeter",
+			Device: testDevice(),
+			State:  map[string]interface{}{"@type": "powerMeterState", "powerConsumption": float64(0), "energyConsumption": energy},
+			Time:   testTime,
+		})
+	}
+	assert.Equal(t, 1, store.calls)
+	assert.Equal(t, []string{
+		"power," + testTags + " energyConsumption=120,energyConsumptionTotal=270,powerConsumption=0",
+		"power," + testTags + " energyConsumption=10,energyConsumptionTotal=280,powerConsumption=0",
+	}, writeAPI.lines())
+}
+
+func TestFluxString(t *testing.T) {
+	assert.Equal(t, `"Plug \"Kitchen\" \\ \${x}"`, fluxString(`Plug "Kitchen" \ ${x}`))
+}
+
+func TestInfluxExporter_exportLatestMotion(t *testing.T) {
+	e, writeAPI := newTestInfluxExporter()
+	e.parseAndExport(&events.Event{
+		ID:     "LatestMotion",
+		Device: testDevice(),
+		State: map[string]interface{}{
+			"@type":                "latestMotionState",
+			"latestMotionDetected": "2020-04-03T19:02:13.683Z",
+		},
+		Time: testTime,
+	})
+	e.parseAndExport(&events.Event{
+		ID:     "LatestMotion",
+		Device: testDevice(),
+		State:  map[string]interface{}{"@type": "latestMotionState"},
+		Time:   testTime,
+	})
+
+	e.parseAndExport(&events.Event{
+		ID:     "LatestMotion",
+		Device: testDevice(),
+		State:  map[string]interface{}{"@type": "latestMotionState", "latestMotionDetected": "yesterday"},
+		Time:   testTime,
+	})
+
+	e.parseAndExport(&events.Event{
+		ID:     "LatestMotion",
+		Device: testDevice(),
+		State: map[string]interface{}{
+			"@type":                "latestMotionState",
+			"latestMotionDetected": "2020-04-03T19:02:13.683Z",
+		},
+		Time:      testTime,
+		Heartbeat: true,
+	})
+
+	assert.Equal(t, []string{"motion," + testTags + " motion=1i"}, writeAPI.lines())
+	assert.Equal(t, time.Date(2020, 4, 3, 19, 2, 13, 683000000, time.UTC), writeAPI.points[0].Time())
+}
+
+func TestInfluxExporter_tags(t *testing.T) {
+	tests := []struct {
+		name     string
+		tagNames []string
+		want     map[string]string
+	}{
+		{
+			name:     "default",
+			tagNames: conf.DefaultTags(),
+			want:     map[string]string{"device": "Plug", "room": "Kitchen", "heartbeat": "true"},
+		},
+		{
+			name:     "ids only",
+			tagNames: []string{"device_id", "room_id"},
+			want: map[string]string{
+				"device_id": "hdm:ZigBee:70ac08fffe6a1b2c",
+				"room_id":   "hz_1",
+				"heartbeat": "true",
+			},
+		},
+		{
+			name:     "metadata",
+			tagNames: []string{"device", "device_id", "model", "serial", "manufacturer", "root_device"},
+			want: map[string]string{
+				"device":    "Plug",
+				"device_id": "hdm:ZigBee:70ac08fffe6a1b2c",
+				"model":     "PSM",
+				"serial":    "70AC08FFFE6A1B2C",
+				"heartbeat": "true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &InfluxExporter{tagNames: tt.tagNames}
+			event := &events.Event{ID: "PowerSwitch", Device: testDevice(), Heartbeat: true}
+			assert.Equal(t, tt.want, e.tags(event))
+		})
+	}
+}
+
+func TestTagNames(t *testing.T) {
+	assert.Equal(t, []string{"device", "room"}, tagNames(&conf.InfluxConfig{}))
+	assert.Equal(t, []string{"device_id"}, tagNames(&conf.InfluxConfig{Tags: []string{"device_id"}}))
+}
+
+func TestClimateControlState_scheduledSetpoint(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	state := ClimateControlState{
+		SetpointTemperatureForLevelComfort: 21.5,
+		SetpointTemperatureForLevelEco:     17.5,
+		Schedule: schedule.Schedule{Profiles: []schedule.Profile{{
+			Day: "MONDAY",
+			SwitchPoints: []schedule.SwitchPoint{
+				{StartTimeMinutes: 0, Value: schedule.Value{TemperatureLevel: schedule.LevelEco}},
+				{StartTimeMinutes: 360, Value: schedule.Value{TemperatureLevel: schedule.LevelComfort}},
+			},
+		}}},
+	}
+	// monday 05:30 UTC is 06:30 in Berlin
+	monday := time.Date(2026, 1, 5, 5, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		want     float64
+	}{
+		{"utc", time.UTC, 17.5},
+		{"berlin", berlin, 21.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := state.scheduledSetpoint(monday, tt.location)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInfluxExporter_exportScheduleChanges(t *testing.T) {
+	e, writeAPI := newTestInfluxExporter()
+	// monday 05:50
+	monday := time.Date(2026, 1, 5, 5, 50, 0, 0, time.UTC)
+	e.parseAndExport(&events.Event{
+		ID:     "RoomClimateControl",
+		Device: testDevice(),
+		State: map[string]interface{}{
+			"@type":                              "climateControlState",
+			"setpointTemperature":                17.5,
+			"setpointTemperatureForLevelComfort": 21.5,
+			"setpointTemperatureForLevelEco":     17.5,
+			"schedule": map[string]interface{}{"profiles": []interface{}{
+				map[string]interface{}{"day": "MONDAY", "switchPoints": []interface{}{
+					map[string]interface{}{"startTimeMinutes": float64(0), "value": map[string]interface{}{"temperatureLevel": "ECO"}},
+					map[string]interface{}{"startTimeMinutes": float64(360), "value": map[string]interface{}{"temperatureLevel": "COMFORT"}},
+				}},
+			}},
+		},
+		Time: monday,
+	})
+	writeAPI.points = nil
+
+	e.exportScheduleChanges(monday.Add(9 * time.Minute))
+	assert.Empty(t, writeAPI.points, "the scheduled setpoint did not change")
+
+	e.exportScheduleChanges(monday.Add(10*time.Minute + 40*time.Second))
+	assert.Equal(t, []string{"room_climate," + testTags + " scheduledSetpointTemperature=21.5"}, writeAPI.lines())
+	assert.Equal(t, monday.Add(10*time.Minute), writeAPI.points[0].Time(), "the point is at the switch point")
+
+	e.exportScheduleChanges(monday.Add(11 * time.Minute))
+	assert.Len(t, writeAPI.points, 1)
+}
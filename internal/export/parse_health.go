@@ -0,0 +1,101 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/events"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type CommunicationQualityState struct {
+	Type    string `json:"@type"`
+	Quality string `json:"quality"`
+}
+
+type DeviceStatusState struct {
+	Status string `json:"status"`
+}
+
+// batteryValue maps the battery faults to OK (0), LOW (1) and CRITICAL (2).
+func batteryValue(faults []string) int {
+	result := 0
+	for _, f := range faults {
+		switch f {
+		case "LOW_BATTERY":
+			result = max(result, 1)
+		case "CRITICAL_LOW", "CRITICALLY_LOW_BATTERY":
+			result = 2
+		}
+	}
+	return result
+}
+
+// qualityValue maps the communication quality to ordinal values, higher
+// values are worse.
+func qualityValue(quality string) (int, bool) {
+	switch quality {
+	case "GOOD":
+		return 0, true
+	case "NORMAL", "MEDIUM":
+		return 1, true
+	case "BAD":
+		return 2, true
+	}
+	return 0, false
+}
+
+func (e *InfluxExporter) exportBatteryLevel(event *events.Event) {
+	p := influxdb2.NewPoint("device_health",
+		e.tags(event),
+		map[string]interface{}{
+			"battery": batteryValue(event.Faults),
+		},
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportCommunicationQuality(event *events.Event) {
+	var parsedState CommunicationQualityState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	quality, ok := qualityValue(parsedState.Quality)
+	if !ok {
+		log.Debug().
+			Str("quality", parsedState.Quality).
+			Str("device", event.Device.Name).
+			Msg("Unknown communication quality")
+		return
+	}
+	p := influxdb2.NewPoint("device_health",
+		e.tags(event),
+		map[string]interface{}{
+			"communicationQuality": quality,
+		},
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportDeviceStatus(event *events.Event) {
+	var parsedState DeviceStatusState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	p := influxdb2.NewPoint("device_health",
+		e.tags(event),
+		map[string]interface{}{
+			"status":    parsedState.Status,
+			"available": boolToInt(parsedState.Status == "AVAILABLE"),
+		},
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
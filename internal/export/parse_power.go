@@ -0,0 +1,195 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/events"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	energySeedTimeout = 10 * time.Second
+	energySeedRange   = "-1y"
+)
+
+type PowerMeterState struct {
+	Type              string  `json:"@type"`
+	PowerConsumption  float64 `json:"powerConsumption"`
+	EnergyConsumption float64 `json:"energyConsumption"`
+}
+
+type PowerSwitchState struct {
+	Type                  string `json:"@type"`
+	SwitchState           string `json:"switchState"`
+	AutomaticPowerOffTime int    `json:"automaticPowerOffTime"`
+}
+
+// monotonicCounter turns counters which are reset by the device, e.g. after a
+// power loss, into counters which never decrease.
+type monotonicCounter struct {
+	last   map[string]float64
+	offset map[string]float64
+	lock   *sync.Mutex
+}
+
+func newMonotonicCounter() *monotonicCounter {
+	return &monotonicCounter{
+		last:   make(map[string]float64),
+		offset: make(map[string]float64),
+		lock:   &sync.Mutex{},
+	}
+}
+
+// Known returns whether the counter has a value for key.
+func (c *monotonicCounter) Known(key string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_, ok := c.last[key]
+	return ok
+}
+
+// Seed continues a counter from a stored raw value and total, e.g. after a
+// restart of the exporter.
+func (c *monotonicCounter) Seed(key string, last float64, total float64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.last[key] = last
+	c.offset[key] = total - last
+}
+
+func (c *monotonicCounter) Update(key string, value float64) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if last, ok := c.last[key]; ok && value < last {
+		log.Info().
+			Str("key", key).
+			Float64("last", last).
+			Float64("value", value).
+			Msg("Counter was reset")
+		c.offset[key] += last
+	}
+	c.last[key] = value
+	return value + c.offset[key]
+}
+
+func (e *InfluxExporter) exportPowerMeter(event *events.Event) {
+	var parsedState PowerMeterState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	e.seedEnergy(event)
+	fields := map[string]interface{}{
+		"powerConsumption":       parsedState.PowerConsumption,
+		"energyConsumption":      parsedState.EnergyConsumption,
+		"energyConsumptionTotal": e.energy.Update(event.Device.ID, parsedState.EnergyConsumption),
+	}
+	p := influxdb2.NewPoint("power",
+		e.tags(event),
+		fields,
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+// seedEnergy continues energyConsumptionTotal of a device from the last value
+// in influx, so the total does not drop when the exporter restarts.
+func (e *InfluxExporter) seedEnergy(event *events.Event) {
+	if e.energyStore == nil || e.energy.Known(event.Device.ID) {
+		return
+	}
+	tags := e.tags(event)
+	tag, value := "device_id", tags["device_id"]
+	if value == "" {
+		tag, value = "device", tags["device"]
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), energySeedTimeout)
+	defer cancel()
+	last, total, ok, err := e.energyStore.lastEnergy(ctx, tag, value)
+	if err != nil {
+		log.Warn().Err(err).Str("device", event.Device.Name).Msg("Could not load last energy total, it starts again")
+		return
+	}
+	if ok {
+		e.energy.Seed(event.Device.ID, last, total)
+	}
+}
+
+func (e *InfluxExporter) exportPowerSwitch(event *events.Event) {
+	var parsedState PowerSwitchState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	fields := map[string]interface{}{
+		"on":                    boolToInt(parsedState.SwitchState == "ON"),
+		"automaticPowerOffTime": parsedState.AutomaticPowerOffTime,
+	}
+	p := influxdb2.NewPoint("power_switch",
+		e.tags(event),
+		fields,
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type energyStore interface {
+	lastEnergy(ctx context.Context, tag string, value string) (last float64, total float64, ok bool, err error)
+}
+
+// influxEnergyStore reads the last stored energy values of a device.
+type influxEnergyStore struct {
+	queryAPI api.QueryAPI
+	bucket   string
+}
+
+func (s *influxEnergyStore) lastEnergy(ctx context.Context, tag string, value string) (float64, float64, bool, error) {
+	query := fmt.Sprintf(`from(bucket: %s)
+  |> range(start: %s)
+  |> filter(fn: (r) => r._measurement == "power" and r[%s] == %s)
+  |> filter(fn: (r) => r._field == "energyConsumption" or r._field == "energyConsumptionTotal")
+  |> group(columns: ["_field"])
+  |> last()`, fluxString(s.bucket), energySeedRange, fluxString(tag), fluxString(value))
+	result, err := s.queryAPI.Query(ctx, query)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer func() {
+		if e := result.Close(); e != nil {
+			log.Err(e).Msg("Error closing query result")
+		}
+	}()
+	values := make(map[string]float64, 2)
+	for result.Next() {
+		if v, ok := result.Record().Value().(float64); ok {
+			values[result.Record().Field()] = v
+		}
+	}
+	if result.Err() != nil {
+		return 0, 0, false, result.Err()
+	}
+	last, hasLast := values["energyConsumption"]
+	total, hasTotal := values["energyConsumptionTotal"]
+	return last, total, hasLast && hasTotal, nil
+}
+
+func fluxString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`).Replace(value) + `"`
+}
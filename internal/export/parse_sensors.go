@@ -0,0 +1,153 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/events"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type LatestMotionState struct {
+	Type                 string `json:"@type"`
+	LatestMotionDetected string `json:"latestMotionDetected"`
+}
+
+type WaterLeakageSensorState struct {
+	Type  string `json:"@type"`
+	State string `json:"state"`
+}
+
+type WaterLeakageSensorTiltState struct {
+	Type                  string `json:"@type"`
+	PushNotificationState string `json:"pushNotificationState"`
+	AcousticSignalState   string `json:"acousticSignalState"`
+}
+
+type ShutterContact2State struct {
+	Type  string `json:"@type"`
+	Value string `json:"value"`
+}
+
+type VibrationSensorState struct {
+	Type        string `json:"@type"`
+	Value       string `json:"value"`
+	Enabled     bool   `json:"enabled"`
+	Sensitivity string `json:"sensitivity"`
+}
+
+func (e *InfluxExporter) exportLatestMotion(event *events.Event) {
+	// a heartbeat repeats the last detection, which was written already
+	if event.Heartbeat {
+		return
+	}
+	var parsedState LatestMotionState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	// Snapshots repeat the state, so only the timestamp of the detection tells
+	// when motion happened.
+	if parsedState.LatestMotionDetected == "" {
+		log.Debug().Str("device", event.Device.Name).Msg("Skipping motion without timestamp")
+		return
+	}
+	timestamp, err := time.Parse(time.RFC3339, parsedState.LatestMotionDetected)
+	if err != nil {
+		log.Err(err).
+			Str("latestMotionDetected", parsedState.LatestMotionDetected).
+			Msg("Error parsing motion timestamp")
+		return
+	}
+	p := influxdb2.NewPoint("motion",
+		e.tags(event),
+		map[string]interface{}{
+			"motion": 1,
+		},
+		timestamp,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportWaterLeakageSensor(event *events.Event) {
+	var parsedState WaterLeakageSensorState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	fields := map[string]interface{}{
+		"state":   parsedState.State,
+		"leakage": boolToInt(parsedState.State == "LEAKAGE_DETECTED"),
+	}
+	p := influxdb2.NewPoint("water_leakage",
+		e.tags(event),
+		fields,
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportWaterLeakageSensorTilt(event *events.Event) {
+	var parsedState WaterLeakageSensorTiltState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	fields := map[string]interface{}{
+		"pushNotification": boolToInt(parsedState.PushNotificationState == "ENABLED"),
+		"acousticSignal":   boolToInt(parsedState.AcousticSignalState == "ENABLED"),
+	}
+	p := influxdb2.NewPoint("water_leakage_tilt",
+		e.tags(event),
+		fields,
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportShutterContact2(event *events.Event) {
+	var parsedState ShutterContact2State
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	p := influxdb2.NewPoint("shutter_contact2",
+		e.tags(event),
+		map[string]interface{}{
+			"open": boolToInt(parsedState.Value == "OPEN"),
+		},
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportVibrationSensor(event *events.Event) {
+	var parsedState VibrationSensorState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	fields := map[string]interface{}{
+		"vibration": boolToInt(parsedState.Value == "VIBRATION_DETECTED"),
+		"enabled":   boolToInt(parsedState.Enabled),
+	}
+	if parsedState.Sensitivity != "" {
+		fields["sensitivity"] = parsedState.Sensitivity
+	}
+	p := influxdb2.NewPoint("vibration",
+		e.tags(event),
+		fields,
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
@@ -0,0 +1,178 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/events"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type AirQualityLevelState struct {
+	Type              string  `json:"@type"`
+	CombinedRating    string  `json:"combinedRating"`
+	Description       string  `json:"description"`
+	Temperature       float64 `json:"temperature"`
+	TemperatureRating string  `json:"temperatureRating"`
+	Humidity          float64 `json:"humidity"`
+	HumidityRating    string  `json:"humidityRating"`
+	Purity            float64 `json:"purity"`
+	PurityRating      string  `json:"purityRating"`
+}
+
+type SmokeDetectorCheckState struct {
+	Type  string `json:"@type"`
+	Value string `json:"value"`
+}
+
+type SmokeSensitivityState struct {
+	Type             string `json:"@type"`
+	SmokeSensitivity string `json:"smokeSensitivity"`
+}
+
+type AlarmState struct {
+	Type  string `json:"@type"`
+	Value string `json:"value"`
+}
+
+// Ratings are mapped to ordinal values, higher values are worse.
+func ratingValue(rating string) (int, bool) {
+	switch rating {
+	case "GOOD":
+		return 0, true
+	case "MEDIUM":
+		return 1, true
+	case "BAD":
+		return 2, true
+	}
+	return 0, false
+}
+
+func sensitivityValue(sensitivity string) (int, bool) {
+	switch sensitivity {
+	case "LOW":
+		return 0, true
+	case "MIDDLE":
+		return 1, true
+	case "HIGH":
+		return 2, true
+	}
+	return 0, false
+}
+
+func alarmValue(alarm string) (int, bool) {
+	switch alarm {
+	case "IDLE_OFF":
+		return 0, true
+	case "INTRUSION_ALARM":
+		return 1, true
+	case "SECONDARY_ALARM":
+		return 2, true
+	case "PRIMARY_ALARM":
+		return 3, true
+	}
+	return 0, false
+}
+
+func (e *InfluxExporter) exportAirQualityLevel(event *events.Event) {
+	var parsedState AirQualityLevelState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	fields := map[string]interface{}{
+		"temperature": parsedState.Temperature,
+		"humidity":    parsedState.Humidity,
+		"purity":      parsedState.Purity,
+	}
+	ratings := map[string]string{
+		"combinedRating":    parsedState.CombinedRating,
+		"temperatureRating": parsedState.TemperatureRating,
+		"humidityRating":    parsedState.HumidityRating,
+		"purityRating":      parsedState.PurityRating,
+	}
+	for field, rating := range ratings {
+		if value, ok := ratingValue(rating); ok {
+			fields[field] = value
+		}
+	}
+	if parsedState.Description != "" {
+		fields["description"] = parsedState.Description
+	}
+	p := influxdb2.NewPoint("air_quality",
+		e.tags(event),
+		fields,
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportSmokeDetectorCheck(event *events.Event) {
+	var parsedState SmokeDetectorCheckState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	fields := map[string]interface{}{
+		"value":      parsedState.Value,
+		"testOk":     boolToInt(parsedState.Value == "SMOKE_TEST_OK"),
+		"testFailed": boolToInt(parsedState.Value == "SMOKE_TEST_FAILED"),
+	}
+	p := influxdb2.NewPoint("smoke_detector_check",
+		e.tags(event),
+		fields,
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportSmokeSensitivity(event *events.Event) {
+	var parsedState SmokeSensitivityState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	sensitivity, ok := sensitivityValue(parsedState.SmokeSensitivity)
+	if !ok {
+		log.Warn().
+			Str("sensitivity", parsedState.SmokeSensitivity).
+			Msg("Unknown smoke sensitivity")
+		return
+	}
+	p := influxdb2.NewPoint("smoke_sensitivity",
+		e.tags(event),
+		map[string]interface{}{
+			"sensitivity": sensitivity,
+		},
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
+
+func (e *InfluxExporter) exportAlarm(event *events.Event) {
+	var parsedState AlarmState
+
+	if err := parseState(&parsedState, event.State, e.parseFailures); err != nil {
+		log.Err(err).Msg("Error parsing state")
+		return
+	}
+
+	fields := map[string]interface{}{
+		"value":  parsedState.Value,
+		"active": boolToInt(parsedState.Value != "IDLE_OFF"),
+	}
+	if level, ok := alarmValue(parsedState.Value); ok {
+		fields["level"] = level
+	}
+	p := influxdb2.NewPoint("alarm",
+		e.tags(event),
+		fields,
+		event.Time,
+	)
+	e.writeAPI.WritePoint(p)
+}
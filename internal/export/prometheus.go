@@ -0,0 +1,146 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/devices"
+	"bosch-data-exporter/internal/events"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+type PrometheusExporter struct {
+	temperature   *prometheus.GaugeVec
+	humidity      *prometheus.GaugeVec
+	valvePosition *prometheus.GaugeVec
+	shutterOpen   *prometheus.GaugeVec
+	roomSetpoint  *prometheus.GaugeVec
+	roomScheduled *scheduleCollector
+	parseFailures *prometheus.CounterVec
+}
+
+func NewPrometheusExporter(config *conf.Config) *PrometheusExporter {
+	return newPrometheusExporter(prometheus.DefaultRegisterer, config.Location())
+}
+
+func newPrometheusExporter(registerer prometheus.Registerer, location *time.Location) *PrometheusExporter {
+	deviceLabels := []string{"device", "room", "model", "serial"}
+	factory := promauto.With(registerer)
+	roomScheduled := newScheduleCollector(newScheduledSetpoints(location), deviceLabels)
+	if registerer != nil {
+		registerer.MustRegister(roomScheduled)
+	}
+	return &PrometheusExporter{
+		roomScheduled: roomScheduled,
+		parseFailures: newParseFailureCount(),
+		temperature: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bosch_temperature_celsius",
+			Help: "Temperature measured by a device",
+		}, deviceLabels),
+		humidity: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bosch_humidity_percent",
+			Help: "Relative humidity measured by a device",
+		}, deviceLabels),
+		valvePosition: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bosch_valve_position_percent",
+			Help: "Opening of a radiator valve",
+		}, deviceLabels),
+		shutterOpen: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bosch_shutter_open",
+			Help: "1 if a door or window contact is open, 0 otherwise",
+		}, deviceLabels),
+		roomSetpoint: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bosch_room_setpoint_celsius",
+			Help: "Setpoint temperature of a room climate control",
+		}, deviceLabels),
+	}
+}
+
+func (e *PrometheusExporter) Name() string {
+	return "prometheus"
+}
+
+func (e *PrometheusExporter) Close() error {
+	return nil
+}
+
+func (e *PrometheusExporter) Export(event *events.Event) {
+	var err error
+	switch event.ID {
+	case "RoomClimateControl":
+		var parsedState ClimateControlState
+		if err = parseState(&parsedState, event.State, e.parseFailures); err == nil {
+			e.roomSetpoint.With(labels(event)).Set(parsedState.SetpointTemperature)
+			e.roomScheduled.scheduled.update(event.Device, parsedState, event.Time)
+		}
+	case "ShutterContact":
+		var parsedState ShutterContactState
+		if err = parseState(&parsedState, event.State, e.parseFailures); err == nil {
+			open := 0.0
+			if parsedState.Value == "OPEN" {
+				open = 1
+			}
+			e.shutterOpen.With(labels(event)).Set(open)
+		}
+	case "TemperatureLevel":
+		var parsedState TemperatureLevelState
+		if err = parseState(&parsedState, event.State, e.parseFailures); err == nil {
+			e.temperature.With(labels(event)).Set(parsedState.Temperature)
+		}
+	case "HumidityLevel":
+		var parsedState HumidityLevelState
+		if err = parseState(&parsedState, event.State, e.parseFailures); err == nil {
+			e.humidity.With(labels(event)).Set(parsedState.Humidity)
+		}
+	case "ValveTappet":
+		var parsedState ValveTappetState
+		if err = parseState(&parsedState, event.State, e.parseFailures); err == nil {
+			e.valvePosition.With(labels(event)).Set(float64(parsedState.Position))
+		}
+	}
+	if err != nil {
+		log.Err(err).Str("id", event.ID).Msg("Error parsing state")
+	}
+}
+
+// scheduleCollector exports the scheduled setpoints at scrape time, so they
+// change at the switch points of the schedules.
+type scheduleCollector struct {
+	desc      *prometheus.Desc
+	scheduled *scheduledSetpoints
+	now       func() time.Time
+}
+
+func newScheduleCollector(scheduled *scheduledSetpoints, deviceLabels []string) *scheduleCollector {
+	return &scheduleCollector{
+		desc: prometheus.NewDesc(
+			"bosch_room_scheduled_setpoint_celsius",
+			"Setpoint temperature a room climate control has according to its schedule",
+			deviceLabels, nil,
+		),
+		scheduled: scheduled,
+		now:       time.Now,
+	}
+}
+
+func (c *scheduleCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *scheduleCollector) Collect(ch chan<- prometheus.Metric) {
+	c.scheduled.each(c.now(), func(device *devices.Device, setpoint float64) {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, setpoint,
+			device.Name, device.Room.Name, device.DeviceModel, device.Serial)
+	})
+}
+
+func labels(event *events.Event) prometheus.Labels {
+	return prometheus.Labels{
+		"device": event.Device.Name,
+		"room":   event.Device.Room.Name,
+		"model":  event.Device.DeviceModel,
+		"serial": event.Device.Serial,
+	}
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/devices"
+	"bosch-data-exporter/internal/events"
+	"bosch-data-exporter/internal/rooms"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusExporter_Export(t *testing.T) {
+	device := &devices.Device{
+		ID:          "hdm:HomeMaticIP:3014F711A000005D58595588",
+		DeviceModel: "TRV",
+		Serial:      "3014F711A000005D58595588",
+		Name:        "Thermostat",
+		Room:        &rooms.Room{ID: "hz_4", Name: "Schlafzimmer"},
+	}
+	labels := prometheus.Labels{
+		"device": "Thermostat",
+		"room":   "Schlafzimmer",
+		"model":  "TRV",
+		"serial": "3014F711A000005D58595588",
+	}
+	e := newPrometheusExporter(nil, time.UTC)
+	failures := testutil.ToFloat64(e.parseFailures.WithLabelValues("humidityLevelState"))
+
+	e.Export(&events.Event{
+		ID:     "TemperatureLevel",
+		Device: device,
+		State:  map[string]interface{}{"@type": "temperatureLevelState", "temperature": 21.5},
+	})
+	e.Export(&events.Event{
+		ID:     "ValveTappet",
+		Device: device,
+		State:  map[string]interface{}{"@type": "valveTappetState", "position": float64(42), "value": "REGULAR"},
+	})
+	e.Export(&events.Event{
+		ID:     "ShutterContact",
+		Device: device,
+		State:  map[string]interface{}{"@type": "shutterContactState", "value": "OPEN"},
+	})
+	e.Export(&events.Event{
+		ID:     "RoomClimateControl",
+		Device: device,
+		State: map[string]interface{}{
+			"@type":                              "climateControlState",
+			"setpointTemperature":                float64(23),
+			"setpointTemperatureForLevelComfort": 21.5,
+			"setpointTemperatureForLevelEco":     17.5,
+			"schedule": map[string]interface{}{"profiles": []interface{}{
+				map[string]interface{}{"day": "MONDAY", "switchPoints": []interface{}{
+					map[string]interface{}{"startTimeMinutes": float64(0), "value": map[string]interface{}{"temperatureLevel": "COMFORT"}},
+				}},
+			}},
+		},
+	})
+	e.Export(&events.Event{
+		ID:     "HumidityLevel",
+		Device: device,
+		State:  map[string]interface{}{"@type": "humidityLevelState", "humidity": "invalid"},
+	})
+
+	assert.Equal(t, 21.5, testutil.ToFloat64(e.temperature.With(labels)))
+	assert.Equal(t, float64(42), testutil.ToFloat64(e.valvePosition.With(labels)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(e.shutterOpen.With(labels)))
+	assert.Equal(t, float64(23), testutil.ToFloat64(e.roomSetpoint.With(labels)))
+	assert.Equal(t, 21.5, testutil.ToFloat64(e.roomScheduled))
+	assert.Equal(t, 0, testutil.CollectAndCount(e.humidity))
+	assert.Equal(t, failures+1, testutil.ToFloat64(e.parseFailures.WithLabelValues("humidityLevelState")))
+}
+
+func TestPrometheusExporter_Export_ScheduledSetpointFollowsSwitchPoints(t *testing.T) {
+	e := newPrometheusExporter(nil, time.UTC)
+	// monday 05:50
+	now := time.Date(2026, 1, 5, 5, 50, 0, 0, time.UTC)
+	e.roomScheduled.now = func() time.Time { return now }
+
+	e.Export(&events.Event{
+		ID:     "RoomClimateControl",
+		Device: &devices.Device{ID: "roomClimateControl_hz_4", Name: "-RoomClimateControl-", Room: &rooms.Room{Name: "Bad"}},
+		Time:   now,
+		State: map[string]interface{}{
+			"@type":                              "climateControlState",
+			"setpointTemperatureForLevelComfort": 21.5,
+			"setpointTemperatureForLevelEco":     17.5,
+			"schedule": map[string]interface{}{"profiles": []interface{}{
+				map[string]interface{}{"day": "MONDAY", "switchPoints": []interface{}{
+					map[string]interface{}{"startTimeMinutes": float64(0), "value": map[string]interface{}{"temperatureLevel": "ECO"}},
+					map[string]interface{}{"startTimeMinutes": float64(360), "value": map[string]interface{}{"temperatureLevel": "COMFORT"}},
+				}},
+			}},
+		},
+	})
+	assert.Equal(t, 17.5, testutil.ToFloat64(e.roomScheduled))
+
+	now = now.Add(20 * time.Minute)
+	assert.Equal(t, 21.5, testutil.ToFloat64(e.roomScheduled), "the switch point at 06:00 applies without a new event")
+}
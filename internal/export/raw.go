@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	rawArraysDrop  = "drop"
+	rawArraysJSON  = "json"
+	rawArraysIndex = "index"
+)
+
+// flattenState turns a service state into influx fields. Nested objects
+// become dotted field names, meta keys starting with @ and null values are
+// skipped and arrays are handled according to arrays.
+func flattenState(state map[string]interface{}, arrays string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	flattenInto(fields, "", state, arrays)
+	return fields
+}
+
+func flattenInto(fields map[string]interface{}, prefix string, value interface{}, arrays string) {
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, child := range v {
+			if strings.HasPrefix(key, "@") {
+				continue
+			}
+			flattenInto(fields, joinKey(prefix, key), child, arrays)
+		}
+	case []interface{}:
+		switch arrays {
+		case rawArraysJSON:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				log.Err(err).Str("field", prefix).Msg("Error encoding array")
+				return
+			}
+			fields[prefix] = string(encoded)
+		case rawArraysIndex:
+			for i, child := range v {
+				flattenInto(fields, joinKey(prefix, strconv.Itoa(i)), child, arrays)
+			}
+		}
+	default:
+		if prefix != "" {
+			fields[prefix] = v
+		}
+	}
+}
+
+func joinKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
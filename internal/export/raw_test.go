@@ -0,0 +1,88 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/events"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlattenState(t *testing.T) {
+	state := map[string]interface{}{
+		"@type":               "climateControlState",
+		"setpointTemperature": 21.5,
+		"boostMode":           false,
+		"roomControlMode":     "HEATING",
+		"low":                 nil,
+		"schedule": map[string]interface{}{
+			"@type":                          "schedule",
+			"profiles":                       []interface{}{map[string]interface{}{"day": "MONDAY"}},
+			"setpointTemperatureForLevelEco": 17.0,
+		},
+		"values": []interface{}{1.0, 2.0},
+	}
+	tests := []struct {
+		name   string
+		arrays string
+		want   map[string]interface{}
+	}{
+		{
+			name:   "drop",
+			arrays: "",
+			want: map[string]interface{}{
+				"setpointTemperature": 21.5,
+				"boostMode":           false,
+				"roomControlMode":     "HEATING",
+				"schedule.setpointTemperatureForLevelEco": 17.0,
+			},
+		},
+		{
+			name:   "json",
+			arrays: "json",
+			want: map[string]interface{}{
+				"setpointTemperature": 21.5,
+				"boostMode":           false,
+				"roomControlMode":     "HEATING",
+				"schedule.profiles":   "[{\"day\":\"MONDAY\"}]",
+				"schedule.setpointTemperatureForLevelEco": 17.0,
+				"values": "[1,2]",
+			},
+		},
+		{
+			name:   "index",
+			arrays: "index",
+			want: map[string]interface{}{
+				"setpointTemperature":                     21.5,
+				"boostMode":                               false,
+				"roomControlMode":                         "HEATING",
+				"schedule.profiles.0.day":                 "MONDAY",
+				"schedule.setpointTemperatureForLevelEco": 17.0,
+				"values.0":                                1.0,
+				"values.1":                                2.0,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, flattenState(state, tt.arrays))
+		})
+	}
+}
+
+func TestInfluxExporter_exportRaw(t *testing.T) {
+	e, writeAPI := newTestInfluxExporter()
+	e.exportRaw(&events.Event{
+		ID:     "ClimateControl",
+		Device: testDevice(),
+		State:  map[string]interface{}{"@type": "climateControlState", "schedule": map[string]interface{}{"low": 16.0}},
+		Time:   testTime,
+	})
+	e.exportRaw(&events.Event{
+		ID:     "BatteryLevel",
+		Device: testDevice(),
+		State:  map[string]interface{}{"@type": "batteryLevelState"},
+		Time:   testTime,
+	})
+
+	assert.Equal(t, []string{"raw_ClimateControl," + testTags + " schedule.low=16"}, writeAPI.lines())
+}
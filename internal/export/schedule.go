@@ -0,0 +1,73 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/devices"
+	"sync"
+	"time"
+)
+
+// scheduleInterval is how often the influx exporter checks for switch points.
+// Switch points are set in minutes.
+const scheduleInterval = time.Minute
+
+// scheduledSetpoints keeps the last state of every room climate control, so
+// the scheduled setpoint follows the switch points of its schedule without
+// new events.
+type scheduledSetpoints struct {
+	location *time.Location
+	lock     *sync.Mutex
+	rooms    map[string]*scheduledRoom
+}
+
+type scheduledRoom struct {
+	device   *devices.Device
+	state    ClimateControlState
+	setpoint float64
+	ok       bool
+}
+
+func newScheduledSetpoints(location *time.Location) *scheduledSetpoints {
+	return &scheduledSetpoints{
+		location: location,
+		lock:     &sync.Mutex{},
+		rooms:    make(map[string]*scheduledRoom),
+	}
+}
+
+// update stores the state of a room climate control and returns its scheduled
+// setpoint at the given time.
+func (s *scheduledSetpoints) update(device *devices.Device, state ClimateControlState, t time.Time) (float64, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	room := &scheduledRoom{device: device, state: state}
+	room.setpoint, room.ok = state.scheduledSetpoint(t, s.location)
+	s.rooms[device.ID] = room
+	return room.setpoint, room.ok
+}
+
+// each calls f for every room climate control that has a scheduled setpoint
+// at the given time.
+func (s *scheduledSetpoints) each(t time.Time, f func(device *devices.Device, setpoint float64)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, room := range s.rooms {
+		if setpoint, ok := room.state.scheduledSetpoint(t, s.location); ok {
+			f(room.device, setpoint)
+		}
+	}
+}
+
+// changed calls f for every room climate control whose scheduled setpoint at
+// the given time differs from the last one, and remembers the new setpoint.
+func (s *scheduledSetpoints) changed(t time.Time, f func(device *devices.Device, setpoint float64)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, room := range s.rooms {
+		setpoint, ok := room.state.scheduledSetpoint(t, s.location)
+		if !ok || (room.ok && setpoint == room.setpoint) {
+			continue
+		}
+		room.setpoint, room.ok = setpoint, true
+		f(room.device, setpoint)
+	}
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/events"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultQueueSize = 100
+
+type Sink interface {
+	Name() string
+	Export(event *events.Event)
+	Close() error
+}
+
+func NewSinks(config *conf.Config) ([]Sink, error) {
+	sinks := make([]Sink, 0)
+	if config.InfluxConfig != nil {
+		influx, err := NewInfluxExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, influx)
+	}
+	if config.PrometheusConfig != nil {
+		sinks = append(sinks, NewPrometheusExporter(config))
+	}
+	if config.FileConfig != nil {
+		file, err := NewFileExporter(config)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, file)
+	}
+	return sinks, nil
+}
+
+type sinkWorker struct {
+	sink  Sink
+	queue chan *events.Event
+}
+
+// FanOut hands every event to all sinks. Each sink has its own queue and
+// goroutine, so a slow or failing sink only drops its own events.
+type FanOut struct {
+	workers      []*sinkWorker
+	droppedCount *prometheus.CounterVec
+	wg           *sync.WaitGroup
+}
+
+func NewFanOut(queueSize int, sinks ...Sink) *FanOut {
+	return newFanOut(
+		queueSize,
+		promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "bosch_sink_dropped_events_total",
+			Help: "Number of events dropped because the queue of a sink was full",
+		}, []string{"sink"}),
+		sinks...,
+	)
+}
+
+func newFanOut(queueSize int, droppedCount *prometheus.CounterVec, sinks ...Sink) *FanOut {
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	workers := make([]*sinkWorker, 0, len(sinks))
+	for _, s := range sinks {
+		log.Info().
+			Str("sink", s.Name()).
+			Int("queueSize", queueSize).
+			Msg("Adding export sink")
+		workers = append(workers, &sinkWorker{
+			sink:  s,
+			queue: make(chan *events.Event, queueSize),
+		})
+	}
+	return &FanOut{
+		workers:      workers,
+		droppedCount: droppedCount,
+		wg:           &sync.WaitGroup{},
+	}
+}
+
+// Start launches one goroutine per sink and returns immediately.
+func (f *FanOut) Start() {
+	for _, w := range f.workers {
+		f.wg.Add(1)
+		go f.run(w)
+	}
+}
+
+// Close waits until all queued events are exported and closes the sinks.
+// Export must not be called afterwards.
+func (f *FanOut) Close() {
+	for _, w := range f.workers {
+		close(w.queue)
+	}
+	f.wg.Wait()
+	for _, w := range f.workers {
+		if err := w.sink.Close(); err != nil {
+			log.Err(err).Str("sink", w.sink.Name()).Msg("Error closing sink")
+		}
+	}
+}
+
+func (f *FanOut) Export(event *events.Event) {
+	for _, w := range f.workers {
+		select {
+		case w.queue <- event:
+		default:
+			f.droppedCount.WithLabelValues(w.sink.Name()).Inc()
+			log.Warn().
+				Str("sink", w.sink.Name()).
+				Str("id", event.ID).
+				Msg("Sink queue full. Dropping event")
+		}
+	}
+}
+
+func (f *FanOut) run(w *sinkWorker) {
+	defer f.wg.Done()
+	for event := range w.queue {
+		exportSafe(w.sink, event)
+	}
+}
+
+func exportSafe(sink Sink, event *events.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Interface("panic", r).
+				Str("sink", sink.Name()).
+				Str("id", event.ID).
+				Msg("Sink panicked while exporting event")
+		}
+	}()
+	sink.Export(event)
+}
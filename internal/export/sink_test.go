@@ -0,0 +1,87 @@
+package export
+
+import (
+	"bosch-data-exporter/internal/events"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSink struct {
+	name       string
+	mockExport func(*events.Event)
+	closed     bool
+}
+
+func (m *mockSink) Name() string {
+	return m.name
+}
+
+func (m *mockSink) Export(event *events.Event) {
+	m.mockExport(event)
+}
+
+func (m *mockSink) Close() error {
+	m.closed = true
+	return nil
+}
+
+func newTestDroppedCount() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{Name: "dropped"}, []string{"sink"})
+}
+
+func TestFanOut_Export(t *testing.T) {
+	received := make(chan string, 10)
+	block := make(chan struct{})
+	defer close(block)
+
+	fast := &mockSink{name: "fast", mockExport: func(event *events.Event) {
+		received <- event.ID
+	}}
+	slow := &mockSink{name: "slow", mockExport: func(event *events.Event) {
+		<-block
+	}}
+	failing := &mockSink{name: "failing", mockExport: func(event *events.Event) {
+		panic("test")
+	}}
+	dropped := newTestDroppedCount()
+	f := newFanOut(1, dropped, slow, failing, fast)
+	f.Start()
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		f.Export(&events.Event{ID: id})
+		select {
+		case got := <-received:
+			assert.Equal(t, id, got)
+		case <-time.After(time.Second):
+			assert.Fail(t, "fast sink did not receive event", id)
+		}
+	}
+	assert.GreaterOrEqual(t, testutil.ToFloat64(dropped.WithLabelValues("slow")), float64(3))
+	assert.Equal(t, float64(0), testutil.ToFloat64(dropped.WithLabelValues("fast")))
+}
+
+func TestNewFanOut_DefaultQueueSize(t *testing.T) {
+	f := newFanOut(0, newTestDroppedCount(), &mockSink{name: "test"})
+	assert.Len(t, f.workers, 1)
+	assert.Equal(t, defaultQueueSize, cap(f.workers[0].queue))
+}
+
+func TestFanOut_Close(t *testing.T) {
+	exported := make([]string, 0)
+	sink := &mockSink{name: "test", mockExport: func(event *events.Event) {
+		time.Sleep(10 * time.Millisecond)
+		exported = append(exported, event.ID)
+	}}
+	f := newFanOut(10, newTestDroppedCount(), sink)
+	f.Start()
+	f.Export(&events.Event{ID: "a"})
+	f.Export(&events.Event{ID: "b"})
+	f.Close()
+
+	assert.Equal(t, []string{"a", "b"}, exported)
+	assert.True(t, sink.closed)
+}
@@ -0,0 +1,414 @@
+// Package fakeshc is an in-process fake of the Bosch Smart Home Controller.
+// It serves the endpoints used by the exporter over mutual TLS, so the whole
+// flow of pairing, subscribing, long polling and resolving devices can be
+// tested without hardware.
+package fakeshc
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultPassword    = "password"
+	defaultPollTimeout = 30 * time.Second
+
+	invalidSubscriptionCode = -32001
+	invalidRequestCode      = -32600
+	methodNotFoundCode      = -32601
+)
+
+type Options struct {
+	// Password is the system password expected when pairing a client.
+	Password string
+	// PollTimeout caps the time a long poll waits for events.
+	PollTimeout time.Duration
+}
+
+type Room struct {
+	ID   string
+	Name string
+}
+
+type Device struct {
+	ID           string
+	RootDeviceID string
+	RoomID       string
+	Name         string
+	DeviceModel  string
+	Manufacturer string
+	Serial       string
+	Profile      string
+	Status       string
+}
+
+// Event is a change of a device service. It updates the state returned by
+// /smarthome/devices/services and is delivered to every subscription.
+type Event struct {
+	DeviceID  string
+	ServiceID string
+	State     map[string]interface{}
+	Faults    []string
+}
+
+type Client struct {
+	ID   string
+	Name string
+}
+
+type serviceKey struct {
+	deviceID  string
+	serviceID string
+}
+
+type subscription struct {
+	queue  []Event
+	notify chan struct{}
+}
+
+type Server struct {
+	server      *httptest.Server
+	password    string
+	pollTimeout time.Duration
+
+	lock          *sync.Mutex
+	rooms         []Room
+	devices       []Device
+	clients       []Client
+	services      map[serviceKey]Event
+	serviceOrder  []serviceKey
+	subscriptions map[string]*subscription
+	nextID        int
+	failures      map[string][]int
+	rpcFailures   []rpcError
+	injected      int
+	closed        chan struct{}
+}
+
+// New creates a server that is not listening yet. Add rooms and devices and
+// call Start or Listen afterwards.
+func New(opts Options) *Server {
+	if opts.Password == "" {
+		opts.Password = defaultPassword
+	}
+	if opts.PollTimeout <= 0 {
+		opts.PollTimeout = defaultPollTimeout
+	}
+	s := &Server{
+		password:      opts.Password,
+		pollTimeout:   opts.PollTimeout,
+		lock:          &sync.Mutex{},
+		services:      make(map[serviceKey]Event),
+		subscriptions: make(map[string]*subscription),
+		failures:      make(map[string][]int),
+		closed:        make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/smarthome/clients", s.handleClients)
+	mux.HandleFunc("/smarthome/rooms", s.requireClient(s.handleRooms))
+	mux.HandleFunc("/smarthome/devices", s.requireClient(s.handleDevices))
+	mux.HandleFunc("/smarthome/devices/services", s.requireClient(s.handleServices))
+	mux.HandleFunc("/remote/json-rpc", s.requireClient(s.handleRPC))
+	s.server = httptest.NewUnstartedServer(s.injectFailures(mux))
+	s.server.TLS = &tls.Config{
+		ClientAuth: tls.RequestClientCert,
+		MinVersion: tls.VersionTLS12,
+	}
+	return s
+}
+
+// Start listens on a random local port.
+func (s *Server) Start() {
+	s.server.StartTLS()
+	log.Info().Str("url", s.URL()).Msg("Fake controller started")
+}
+
+// Listen listens on the given address, e.g. ":8444".
+func (s *Server) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if e := s.server.Listener.Close(); e != nil {
+		return e
+	}
+	s.server.Listener = listener
+	s.Start()
+	return nil
+}
+
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close stops all pending long polls and the server.
+func (s *Server) Close() {
+	close(s.closed)
+	s.server.Close()
+}
+
+func (s *Server) AddRoom(rooms ...Room) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.rooms = append(s.rooms, rooms...)
+}
+
+func (s *Server) AddDevice(devices ...Device) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.devices = append(s.devices, devices...)
+}
+
+// AddClient registers a client as if it was paired before.
+func (s *Server) AddClient(clients ...Client) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clients = append(s.clients, clients...)
+}
+
+// SetDeviceStatus changes the availability of a device, e.g. UNAVAILABLE.
+func (s *Server) SetDeviceStatus(deviceID string, status string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := range s.devices {
+		if s.devices[i].ID == deviceID {
+			s.devices[i].Status = status
+		}
+	}
+}
+
+// Emit updates the service states and queues the events for every active
+// subscription.
+func (s *Server) Emit(events ...Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, e := range events {
+		key := serviceKey{deviceID: e.DeviceID, serviceID: e.ServiceID}
+		if _, ok := s.services[key]; !ok {
+			s.serviceOrder = append(s.serviceOrder, key)
+		}
+		s.services[key] = e
+	}
+	for _, sub := range s.subscriptions {
+		sub.queue = append(sub.queue, events...)
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// FailNext answers the next request to path with the given HTTP status.
+// Several calls queue several failures.
+func (s *Server) FailNext(path string, status int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures[path] = append(s.failures[path], status)
+}
+
+// FailNextRPC answers the next JSON-RPC call with an RPC error.
+func (s *Server) FailNextRPC(code int, message string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.rpcFailures = append(s.rpcFailures, rpcError{Code: code, Message: message})
+}
+
+// ExpireSubscriptions drops all poll subscriptions, like the controller does
+// after a restart or when a poll ID was not used for a while.
+func (s *Server) ExpireSubscriptions() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for id, sub := range s.subscriptions {
+		close(sub.notify)
+		delete(s.subscriptions, id)
+	}
+}
+
+// Subscriptions returns the number of active poll subscriptions.
+func (s *Server) Subscriptions() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.subscriptions)
+}
+
+// InjectedFailures returns the number of requests answered with a failure
+// queued by FailNext or FailNextRPC.
+func (s *Server) InjectedFailures() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.injected
+}
+
+// Clients returns the paired clients.
+func (s *Server) Clients() []Client {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Client(nil), s.clients...)
+}
+
+func (s *Server) injectFailures(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		var status int
+		if queued := s.failures[r.URL.Path]; len(queued) > 0 {
+			status = queued[0]
+			s.failures[r.URL.Path] = queued[1:]
+			s.injected++
+		}
+		s.lock.Unlock()
+		if status != 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireClient rejects requests without a client certificate. Only pairing
+// works without one.
+func (s *Server) requireClient(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleClients(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.requireClient(s.listClients)(w, r)
+	case http.MethodPost:
+		s.pairClient(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) listClients(w http.ResponseWriter, _ *http.Request) {
+	s.lock.Lock()
+	response := make([]map[string]interface{}, 0, len(s.clients))
+	for _, c := range s.clients {
+		response = append(response, map[string]interface{}{
+			"@type":       "client",
+			"id":          c.ID,
+			"name":        c.Name,
+			"primaryRole": "ROLE_RESTRICTED_CLIENT",
+		})
+	}
+	s.lock.Unlock()
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) pairClient(w http.ResponseWriter, r *http.Request) {
+	password, err := base64.StdEncoding.DecodeString(r.Header.Get("Systempassword"))
+	if err != nil || string(password) != s.password {
+		http.Error(w, "invalid system password", http.StatusUnauthorized)
+		return
+	}
+	var request struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Certificate string `json:"certificate"`
+	}
+	if e := json.NewDecoder(r.Body).Decode(&request); e != nil || request.ID == "" {
+		http.Error(w, "invalid client", http.StatusBadRequest)
+		return
+	}
+	if !strings.Contains(request.Certificate, "BEGIN CERTIFICATE") {
+		http.Error(w, "invalid certificate", http.StatusBadRequest)
+		return
+	}
+	s.AddClient(Client{ID: request.ID, Name: request.Name})
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) handleRooms(w http.ResponseWriter, _ *http.Request) {
+	s.lock.Lock()
+	response := make([]map[string]interface{}, 0, len(s.rooms))
+	for _, r := range s.rooms {
+		response = append(response, map[string]interface{}{
+			"@type":  "room",
+			"id":     r.ID,
+			"iconId": "icon_room_living_room",
+			"name":   r.Name,
+		})
+	}
+	s.lock.Unlock()
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleDevices(w http.ResponseWriter, _ *http.Request) {
+	s.lock.Lock()
+	response := make([]map[string]interface{}, 0, len(s.devices))
+	for _, d := range s.devices {
+		status := d.Status
+		if status == "" {
+			status = "AVAILABLE"
+		}
+		response = append(response, map[string]interface{}{
+			"@type":        "device",
+			"id":           d.ID,
+			"rootDeviceId": d.RootDeviceID,
+			"roomId":       d.RoomID,
+			"name":         d.Name,
+			"deviceModel":  d.DeviceModel,
+			"manufacturer": d.Manufacturer,
+			"serial":       d.Serial,
+			"profile":      d.Profile,
+			"status":       status,
+		})
+	}
+	s.lock.Unlock()
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleServices(w http.ResponseWriter, _ *http.Request) {
+	s.lock.Lock()
+	response := make([]map[string]interface{}, 0, len(s.serviceOrder))
+	for _, key := range s.serviceOrder {
+		response = append(response, serviceData(s.services[key]))
+	}
+	s.lock.Unlock()
+	writeJSON(w, http.StatusOK, response)
+}
+
+func serviceData(e Event) map[string]interface{} {
+	data := map[string]interface{}{
+		"@type":    "DeviceServiceData",
+		"id":       e.ServiceID,
+		"deviceId": e.DeviceID,
+		"path":     fmt.Sprintf("/devices/%s/services/%s", e.DeviceID, e.ServiceID),
+	}
+	if e.State != nil {
+		data["state"] = e.State
+	}
+	if len(e.Faults) > 0 {
+		entries := make([]map[string]string, 0, len(e.Faults))
+		for _, f := range e.Faults {
+			entries = append(entries, map[string]string{"type": f, "category": "WARNING"})
+		}
+		data["faults"] = map[string]interface{}{"entries": entries}
+	}
+	return data
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Err(err).Msg("Error writing fake controller response")
+	}
+}
@@ -0,0 +1,41 @@
+package fakeshc
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_RequireClientCertificate(t *testing.T) {
+	s := New(Options{})
+	s.Start()
+	defer s.Close()
+
+	resp, err := s.server.Client().Get(s.URL() + "/smarthome/devices")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestServer_FailNext(t *testing.T) {
+	s := New(Options{})
+	s.Start()
+	defer s.Close()
+	s.FailNext("/smarthome/clients", http.StatusServiceUnavailable)
+
+	post := func() int {
+		req, err := http.NewRequest(http.MethodPost, s.URL()+"/smarthome/clients", strings.NewReader("{}"))
+		require.NoError(t, err)
+		resp, err := s.server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, 0, s.InjectedFailures())
+	assert.Equal(t, http.StatusServiceUnavailable, post())
+	assert.Equal(t, http.StatusUnauthorized, post())
+	assert.Equal(t, 1, s.InjectedFailures())
+}
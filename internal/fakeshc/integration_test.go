@@ -0,0 +1,202 @@
+package fakeshc_test
+
+import (
+	"bosch-data-exporter/internal/cache"
+	"bosch-data-exporter/internal/client"
+	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/devices"
+	"bosch-data-exporter/internal/events"
+	"bosch-data-exporter/internal/export"
+	"bosch-data-exporter/internal/fakeshc"
+	"bosch-data-exporter/internal/polling"
+	"bosch-data-exporter/internal/register"
+	"bosch-data-exporter/internal/rooms"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const waitTimeout = 10 * time.Second
+
+// channelSink is a second sink next to the file sink, so the test can wait for
+// exported events.
+type channelSink chan *events.Event
+
+func (c channelSink) Name() string {
+	return "channel"
+}
+
+func (c channelSink) Export(event *events.Event) {
+	c <- event
+}
+
+func (c channelSink) Close() error {
+	return nil
+}
+
+// waitFor returns the first exported event of the service, other events are
+// skipped.
+func waitFor(t *testing.T, exported channelSink, serviceID string) *events.Event {
+	t.Helper()
+	timeout := time.After(waitTimeout)
+	for {
+		select {
+		case e := <-exported:
+			if e.ID == serviceID {
+				return e
+			}
+		case <-timeout:
+			require.FailNow(t, "event was not exported", serviceID)
+		}
+	}
+}
+
+func TestIntegration(t *testing.T) {
+	shc := fakeshc.New(fakeshc.Options{Password: "secret", PollTimeout: time.Second})
+	shc.AddRoom(fakeshc.Room{ID: "hz_1", Name: "Kitchen"})
+	shc.AddDevice(fakeshc.Device{
+		ID:          "hdm:HomeMaticIP:3014F711A0000496D858A8B1",
+		RoomID:      "hz_1",
+		Name:        "Thermostat",
+		DeviceModel: "TRV_GEN2",
+	})
+	shc.Emit(fakeshc.Event{
+		DeviceID:  "hdm:HomeMaticIP:3014F711A0000496D858A8B1",
+		ServiceID: "TemperatureLevel",
+		State:     map[string]interface{}{"@type": "temperatureLevelState", "temperature": 20.5},
+	})
+	shc.Start()
+	defer shc.Close()
+
+	dir := t.TempDir()
+	exportPath := filepath.Join(dir, "events.jsonl")
+	config := &conf.Config{
+		DeviceUpdateInterval: 10,
+		PollIDUpdateInterval: 30,
+		ClientCertPath:       filepath.Join(dir, "client-cert.pem"),
+		ClientKeyPath:        filepath.Join(dir, "client-key.pem"),
+		BoschConfig: &conf.BoschConfig{
+			ClientID:   "oss_go_exporter",
+			ClientName: "OSS Go Exporter",
+			BaseURL:    shc.URL(),
+			PairingURL: shc.URL(),
+		},
+		FileConfig: &conf.FileConfig{Path: exportPath},
+	}
+	require.Error(t, register.Pair(config, "wrong"))
+	require.NoError(t, register.Pair(config, "secret"))
+	assert.Equal(t, []fakeshc.Client{{ID: "oss_go_exporter", Name: "OSS Go Exporter"}}, shc.Clients())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	httpClient := client.Init(config)
+	require.NoError(t, register.Register(ctx, httpClient, config))
+
+	fileSink, err := export.NewFileExporter(config)
+	require.NoError(t, err)
+	exported := make(channelSink, 100)
+	fanOut := export.NewFanOut(100, fileSink, exported)
+	fanOut.Start()
+
+	clock := events.NewClock(nil)
+	roomPolling := rooms.NewRoomPolling(httpClient, config)
+	cachedRooms := cache.New("rooms", roomPolling.Get, time.Hour)
+	devicePolling := devices.NewDevicePolling(httpClient, cachedRooms, config)
+	cachedDevices := cache.New("devices", events.ExportDeviceStatus(devicePolling.Get, fanOut, clock), time.Hour)
+	pollID := polling.New(httpClient, config)
+	cachedPollID := cache.New("pollID", pollID.Get, time.Hour)
+
+	eventPolling := events.NewSmartHomeEventPolling(
+		httpClient, cachedDevices, cachedPollID, pollID, fanOut, clock, config,
+	)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		eventPolling.Start(ctx)
+	}()
+
+	snapshot := waitFor(t, exported, "TemperatureLevel")
+	assert.Equal(t, "Thermostat", snapshot.Device.Name)
+	assert.Equal(t, "Kitchen", snapshot.Device.Room.Name)
+	assert.Equal(t, 20.5, snapshot.State["temperature"])
+
+	require.Eventually(t, func() bool { return shc.Subscriptions() == 1 }, waitTimeout, 10*time.Millisecond)
+	shc.Emit(fakeshc.Event{
+		DeviceID:  "hdm:HomeMaticIP:3014F711A0000496D858A8B1",
+		ServiceID: "ValveTappet",
+		State:     map[string]interface{}{"@type": "valveTappetState", "position": 42},
+	})
+	valve := waitFor(t, exported, "ValveTappet")
+	assert.Equal(t, "Thermostat", valve.Device.Name)
+	assert.Equal(t, float64(42), valve.State["position"])
+
+	// the poller renews the subscription and exports a snapshot again
+	shc.ExpireSubscriptions()
+	again := waitFor(t, exported, "ValveTappet")
+	assert.True(t, again.Time.After(valve.Time))
+	require.Eventually(t, func() bool { return shc.Subscriptions() == 1 }, waitTimeout, 10*time.Millisecond)
+
+	shc.Emit(fakeshc.Event{
+		DeviceID:  "unknown",
+		ServiceID: "HumidityLevel",
+		State:     map[string]interface{}{"@type": "humidityLevelState", "humidity": 55.0},
+	})
+	unknown := waitFor(t, exported, "HumidityLevel")
+	assert.Equal(t, devices.DefaultDevice(), unknown.Device)
+
+	// only long polls call the json-rpc endpoint while the subscription is
+	// valid, so one of them fails and the poller subscribes again
+	failed := cachedPollID.Current()
+	shc.FailNext("/remote/json-rpc", http.StatusServiceUnavailable)
+	require.Eventually(t, func() bool { return shc.InjectedFailures() == 1 }, waitTimeout, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return cachedPollID.Current() != failed }, waitTimeout, 10*time.Millisecond)
+	assert.Equal(t, 1, shc.Subscriptions(), "the failed subscription is removed")
+	waitFor(t, exported, "TemperatureLevel")
+
+	cancel()
+	wg.Wait()
+	require.NoError(t, pollID.Unsubscribe(context.Background(), cachedPollID.Current()))
+	assert.Equal(t, 0, shc.Subscriptions())
+
+	fanOut.Close()
+	assertExportedToFile(t, exportPath)
+}
+
+// assertExportedToFile checks the events the file sink wrote.
+func assertExportedToFile(t *testing.T, path string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	byID := make(map[string][]map[string]interface{})
+	times := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		id, _ := record["id"].(string)
+		byID[id] = append(byID[id], record)
+		timestamp, _ := record["time"].(string)
+		assert.False(t, times[timestamp], "every event has its own time")
+		times[timestamp] = true
+	}
+
+	require.NotEmpty(t, byID["TemperatureLevel"])
+	temperature := byID["TemperatureLevel"][0]
+	assert.Equal(t, "Thermostat", temperature["device"])
+	assert.Equal(t, "Kitchen", temperature["room"])
+	assert.Equal(t, map[string]interface{}{"@type": "temperatureLevelState", "temperature": 20.5}, temperature["state"])
+	require.NotEmpty(t, byID["ValveTappet"])
+	assert.Equal(t, float64(42), byID["ValveTappet"][0]["state"].(map[string]interface{})["position"])
+	require.NotEmpty(t, byID[events.DeviceStatusID])
+	assert.Equal(t, "hdm:HomeMaticIP:3014F711A0000496D858A8B1", byID[events.DeviceStatusID][0]["deviceId"])
+	require.NotEmpty(t, byID["HumidityLevel"])
+}
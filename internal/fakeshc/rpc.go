@@ -0,0 +1,139 @@
+package fakeshc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type rpcRequest struct {
+	Jsonrpc string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	Jsonrpc string      `json:"jsonrpc"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *rpcError   `json:"error,omitempty"`
+}
+
+func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
+	var requests []rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&requests); err != nil || len(requests) == 0 {
+		writeRPCError(w, rpcError{Code: invalidRequestCode, Message: "Invalid Request"})
+		return
+	}
+	s.lock.Lock()
+	var injected *rpcError
+	if len(s.rpcFailures) > 0 {
+		injected = &s.rpcFailures[0]
+		s.rpcFailures = s.rpcFailures[1:]
+		s.injected++
+	}
+	s.lock.Unlock()
+	if injected != nil {
+		writeRPCError(w, *injected)
+		return
+	}
+
+	request := requests[0]
+	switch request.Method {
+	case "RE/subscribe":
+		writeJSON(w, http.StatusOK, []rpcResponse{{Jsonrpc: "2.0", Result: s.subscribe()}})
+	case "RE/unsubscribe":
+		s.unsubscribe(stringParam(request.Params, 0))
+		writeJSON(w, http.StatusOK, []rpcResponse{{Jsonrpc: "2.0", Result: nil}})
+	case "RE/longPoll":
+		s.longPoll(w, r, request)
+	default:
+		writeRPCError(w, rpcError{Code: methodNotFoundCode, Message: "Method not found: " + request.Method})
+	}
+}
+
+func (s *Server) subscribe() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.nextID++
+	id := fmt.Sprintf("fake-poll-%d", s.nextID)
+	s.subscriptions[id] = &subscription{notify: make(chan struct{}, 1)}
+	return id
+}
+
+func (s *Server) unsubscribe(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if sub, ok := s.subscriptions[id]; ok {
+		close(sub.notify)
+		delete(s.subscriptions, id)
+	}
+}
+
+// longPoll waits until events are queued for the subscription, the timeout
+// passed or the subscription expired.
+func (s *Server) longPoll(w http.ResponseWriter, r *http.Request, request rpcRequest) {
+	id := stringParam(request.Params, 0)
+	timeout := s.pollTimeout
+	if seconds, ok := numberParam(request.Params, 1); ok {
+		timeout = min(time.Duration(seconds*float64(time.Second)), s.pollTimeout)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		s.lock.Lock()
+		sub, ok := s.subscriptions[id]
+		if !ok {
+			s.lock.Unlock()
+			writeRPCError(w, rpcError{Code: invalidSubscriptionCode, Message: "No subscription with id: " + id})
+			return
+		}
+		if len(sub.queue) > 0 {
+			result := make([]map[string]interface{}, 0, len(sub.queue))
+			for _, e := range sub.queue {
+				result = append(result, serviceData(e))
+			}
+			sub.queue = nil
+			s.lock.Unlock()
+			writeJSON(w, http.StatusOK, []rpcResponse{{Jsonrpc: "2.0", Result: result}})
+			return
+		}
+		s.lock.Unlock()
+
+		select {
+		case <-sub.notify:
+		case <-timer.C:
+			writeJSON(w, http.StatusOK, []rpcResponse{{Jsonrpc: "2.0", Result: []interface{}{}}})
+			return
+		case <-r.Context().Done():
+			return
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func writeRPCError(w http.ResponseWriter, err rpcError) {
+	writeJSON(w, http.StatusOK, []rpcResponse{{Jsonrpc: "2.0", Error: &err}})
+}
+
+func stringParam(params []interface{}, i int) string {
+	if i >= len(params) {
+		return ""
+	}
+	value, _ := params[i].(string)
+	return value
+}
+
+func numberParam(params []interface{}, i int) (float64, bool) {
+	if i >= len(params) {
+		return 0, false
+	}
+	value, ok := params[i].(float64)
+	return value, ok
+}
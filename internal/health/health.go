@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusDown = "down"
+
+	checkTimeout = 5 * time.Second
+)
+
+// Checker is implemented by components that can report their health, e.g.
+// export sinks.
+type Checker interface {
+	Health(ctx context.Context) error
+}
+
+type CheckFunc func(ctx context.Context) error
+
+type Component struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type Response struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+type check struct {
+	name  string
+	check CheckFunc
+}
+
+// Handler serves /healthz and /readyz. Liveness only reports that the
+// process serves requests, readiness runs all registered checks.
+type Handler struct {
+	checks []check
+	lock   *sync.Mutex
+}
+
+func New() *Handler {
+	return &Handler{
+		checks: make([]check, 0),
+		lock:   &sync.Mutex{},
+	}
+}
+
+func (h *Handler) Add(name string, c CheckFunc) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.checks = append(h.checks, check{name: name, check: c})
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", h.Live)
+	mux.HandleFunc("/readyz", h.Ready)
+}
+
+func (h *Handler) Live(w http.ResponseWriter, _ *http.Request) {
+	writeResponse(w, http.StatusOK, &Response{Status: StatusOK})
+}
+
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+	response := h.Check(ctx)
+	status := http.StatusOK
+	if response.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeResponse(w, status, response)
+}
+
+// Check runs all checks concurrently. The result is only ok, if every
+// component is ok.
+func (h *Handler) Check(ctx context.Context) *Response {
+	h.lock.Lock()
+	checks := append([]check(nil), h.checks...)
+	h.lock.Unlock()
+
+	results := make([]Component, len(checks))
+	wg := &sync.WaitGroup{}
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = Component{Status: StatusOK}
+			if err := c.check(ctx); err != nil {
+				results[i] = Component{Status: StatusDown, Reason: err.Error()}
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	response := &Response{Status: StatusOK, Components: make(map[string]Component, len(checks))}
+	for i, c := range checks {
+		response.Components[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			response.Status = StatusDown
+		}
+	}
+	return response
+}
+
+func writeResponse(w http.ResponseWriter, status int, response *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Err(err).Msg("Error writing health response")
+	}
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	h := New()
+	mux := http.NewServeMux()
+	h.Register(mux)
+	h.Add("shc", func(context.Context) error { return nil })
+
+	tests := []struct {
+		name   string
+		path   string
+		add    *check
+		status int
+		want   *Response
+	}{
+		{
+			name:   "live",
+			path:   "/healthz",
+			status: http.StatusOK,
+			want:   &Response{Status: StatusOK},
+		},
+		{
+			name:   "ready",
+			path:   "/readyz",
+			status: http.StatusOK,
+			want: &Response{
+				Status:     StatusOK,
+				Components: map[string]Component{"shc": {Status: StatusOK}},
+			},
+		},
+		{
+			name: "not ready",
+			path: "/readyz",
+			add: &check{name: "sink_influx", check: func(context.Context) error {
+				return errors.New("connection refused")
+			}},
+			status: http.StatusServiceUnavailable,
+			want: &Response{
+				Status: StatusDown,
+				Components: map[string]Component{
+					"shc":         {Status: StatusOK},
+					"sink_influx": {Status: StatusDown, Reason: "connection refused"},
+				},
+			},
+		},
+		{
+			name:   "live while not ready",
+			path:   "/healthz",
+			status: http.StatusOK,
+			want:   &Response{Status: StatusOK},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.add != nil {
+				h.Add(tt.add.name, tt.add.check)
+			}
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.status, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+			got := &Response{}
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
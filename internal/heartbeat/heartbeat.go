@@ -0,0 +1,90 @@
+package heartbeat
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"bosch-data-exporter/internal/events"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+type exporter interface {
+	Export(event *events.Event)
+}
+
+type stateKey struct {
+	deviceID  string
+	serviceID string
+}
+
+// Store remembers the last state of every device service and re-exports all
+// of them periodically, so stable values keep producing data points.
+type Store struct {
+	target         exporter
+	interval       time.Duration
+	states         map[stateKey]*events.Event
+	lock           *sync.Mutex
+	heartbeatCount prometheus.Counter
+}
+
+func New(target exporter, config *conf.Config) *Store {
+	return &Store{
+		target:   target,
+		interval: time.Duration(config.HeartbeatInterval) * time.Minute,
+		states:   make(map[stateKey]*events.Event),
+		lock:     &sync.Mutex{},
+		heartbeatCount: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "bosch_heartbeat_events_total",
+			Help: "Number of last-known states re-exported as heartbeat",
+		}),
+	}
+}
+
+func (s *Store) Export(event *events.Event) {
+	if s.interval > 0 && !event.Heartbeat && event.Device.ID != "" {
+		s.lock.Lock()
+		s.states[stateKey{deviceID: event.Device.ID, serviceID: event.ID}] = event
+		s.lock.Unlock()
+	}
+	s.target.Export(event)
+}
+
+func (s *Store) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		log.Info().Msg("Heartbeat disabled")
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.emit()
+		}
+	}
+}
+
+func (s *Store) emit() {
+	s.lock.Lock()
+	heartbeats := make([]*events.Event, 0, len(s.states))
+	now := time.Now()
+	for _, e := range s.states {
+		heartbeat := *e
+		heartbeat.Heartbeat = true
+		heartbeat.Time = now
+		heartbeats = append(heartbeats, &heartbeat)
+	}
+	s.lock.Unlock()
+
+	log.Debug().Int("number", len(heartbeats)).Msg("Exporting heartbeat")
+	for _, e := range heartbeats {
+		s.target.Export(e)
+	}
+	s.heartbeatCount.Add(float64(len(heartbeats)))
+}
@@ -0,0 +1,83 @@
+package heartbeat
+
+import (
+	"bosch-data-exporter/internal/devices"
+	"bosch-data-exporter/internal/events"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockExporter struct {
+	exported []*events.Event
+}
+
+func (m *mockExporter) Export(event *events.Event) {
+	m.exported = append(m.exported, event)
+}
+
+func TestStore_Export(t *testing.T) {
+	device := &devices.Device{ID: "roomClimateControl_hz_4"}
+	first := &events.Event{
+		ID:     "TemperatureLevel",
+		Device: device,
+		State:  map[string]interface{}{"temperature": 20.5},
+	}
+	second := &events.Event{
+		ID:     "TemperatureLevel",
+		Device: device,
+		State:  map[string]interface{}{"temperature": 21.0},
+		Time:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	unknown := &events.Event{
+		ID:     "TemperatureLevel",
+		Device: devices.DefaultDevice(),
+		State:  map[string]interface{}{"temperature": 19.0},
+	}
+
+	target := &mockExporter{}
+	s := &Store{
+		target:         target,
+		interval:       time.Minute,
+		states:         make(map[stateKey]*events.Event),
+		lock:           &sync.Mutex{},
+		heartbeatCount: prometheus.NewCounter(prometheus.CounterOpts{Name: "heartbeats"}),
+	}
+	s.Export(first)
+	s.Export(second)
+	s.Export(unknown)
+	assert.Equal(t, []*events.Event{first, second, unknown}, target.exported)
+
+	target.exported = nil
+	s.emit()
+	assert.Len(t, target.exported, 1)
+	assert.True(t, target.exported[0].Time.After(second.Time))
+	target.exported[0].Time = time.Time{}
+	assert.Equal(t, []*events.Event{
+		{
+			ID:        "TemperatureLevel",
+			Device:    device,
+			State:     map[string]interface{}{"temperature": 21.0},
+			Heartbeat: true,
+		},
+	}, target.exported)
+
+	s.Export(target.exported[0])
+	assert.Len(t, s.states, 1)
+	assert.False(t, s.states[stateKey{deviceID: device.ID, serviceID: "TemperatureLevel"}].Heartbeat)
+}
+
+func TestStore_Disabled(t *testing.T) {
+	target := &mockExporter{}
+	s := &Store{
+		target: target,
+		states: make(map[stateKey]*events.Event),
+		lock:   &sync.Mutex{},
+	}
+	s.Export(&events.Event{ID: "TemperatureLevel", Device: &devices.Device{ID: "id"}})
+	assert.Len(t, target.exported, 1)
+	assert.Empty(t, s.states)
+}
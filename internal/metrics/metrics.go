@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewCounter registers a counter with the default registerer. If a counter
+// with the same name is already registered, that one is returned, so several
+// instances of a component can share their metrics.
+func NewCounter(opts prometheus.CounterOpts) prometheus.Counter {
+	return register(prometheus.DefaultRegisterer, prometheus.NewCounter(opts))
+}
+
+// NewCounterVec is like NewCounter for counter vectors.
+func NewCounterVec(opts prometheus.CounterOpts, labels []string) *prometheus.CounterVec {
+	return register(prometheus.DefaultRegisterer, prometheus.NewCounterVec(opts, labels))
+}
+
+// NewGauge is like NewCounter for gauges.
+func NewGauge(opts prometheus.GaugeOpts) prometheus.Gauge {
+	return register(prometheus.DefaultRegisterer, prometheus.NewGauge(opts))
+}
+
+func register[T prometheus.Collector](registerer prometheus.Registerer, collector T) T {
+	err := registerer.Register(collector)
+	if err == nil {
+		return collector
+	}
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		if existing, ok := registered.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+	panic(err)
+}
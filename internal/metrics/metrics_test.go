@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister_Reuse(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	opts := prometheus.CounterOpts{Name: "test_total"}
+
+	first := register(registry, prometheus.NewCounterVec(opts, []string{"label"}))
+	second := register(registry, prometheus.NewCounterVec(opts, []string{"label"}))
+	assert.Same(t, first, second)
+
+	assert.Panics(t, func() {
+		register(registry, prometheus.NewCounterVec(opts, []string{"other"}))
+	})
+}
@@ -38,7 +38,7 @@ func New(client httpClient, config *conf.Config) *PollIDGenerator {
 	}
 }
 
-func (p *PollIDGenerator) Get() (string, error) {
+func (p *PollIDGenerator) Get(ctx context.Context) (string, error) {
 	requestBody := []pollRequest{
 		{
 			Jsonrpc: "2.0",
@@ -57,7 +57,7 @@ func (p *PollIDGenerator) Get() (string, error) {
 		Msg("Creating poll subscription")
 
 	req, err := http.NewRequestWithContext(
-		context.Background(),
+		ctx,
 		http.MethodPost,
 		shcPollURL,
 		bytes.NewReader(requestBodyBytes),
@@ -98,3 +98,50 @@ func (p *PollIDGenerator) Get() (string, error) {
 	log.Info().Str("pollID", pollID).Msg("Created poll subscription")
 	return pollID, nil
 }
+
+func (p *PollIDGenerator) Unsubscribe(ctx context.Context, pollID string) error {
+	if pollID == "" {
+		return nil
+	}
+	requestBody := []pollRequest{
+		{
+			Jsonrpc: "2.0",
+			Method:  "RE/unsubscribe",
+			Params:  []interface{}{pollID},
+		},
+	}
+	requestBodyBytes, err := json.Marshal(requestBody)
+	if err != nil {
+		return err
+	}
+	shcPollURL := fmt.Sprintf("%s/remote/json-rpc", p.baseURL)
+	log.Info().
+		Str("url", shcPollURL).
+		Str("pollID", pollID).
+		Msg("Removing poll subscription")
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		shcPollURL,
+		bytes.NewReader(requestBodyBytes),
+	)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := resp.Body.Close()
+		if e != nil {
+			log.Err(e).Msg("Error closing response body")
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response status of unsubscribe call is not %d, but %d", http.StatusOK, resp.StatusCode)
+	}
+	return nil
+}
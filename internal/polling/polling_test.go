@@ -1,6 +1,7 @@
 package polling
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -95,7 +96,7 @@ func TestPollIDGenerator_Get(t *testing.T) {
 				client:  tt.fields.client,
 				baseURL: tt.fields.baseURL,
 			}
-			got, err := p.Get()
+			got, err := p.Get(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -106,3 +107,33 @@ func TestPollIDGenerator_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestPollIDGenerator_Unsubscribe(t *testing.T) {
+	called := false
+	p := &PollIDGenerator{
+		client: &mockClient{
+			mockDo: func(request *http.Request) (*http.Response, error) {
+				called = true
+				buf := new(strings.Builder)
+				_, e := io.Copy(buf, request.Body)
+				assert.NoError(t, e)
+				assert.JSONEq(t, "[{"+
+					"\"jsonrpc\":\"2.0\","+
+					"\"method\":\"RE/unsubscribe\","+
+					"\"params\":[\"poll-id\"]"+
+					"}]", buf.String())
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("[{\"result\":null,\"jsonrpc\":\"2.0\"}]")),
+				}, nil
+			},
+		},
+		baseURL: "http://localhost:8080",
+	}
+	assert.NoError(t, p.Unsubscribe(context.Background(), "poll-id"))
+	assert.True(t, called)
+
+	called = false
+	assert.NoError(t, p.Unsubscribe(context.Background(), ""))
+	assert.False(t, called)
+}
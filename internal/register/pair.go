@@ -0,0 +1,184 @@
+package register
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	pairingPort         = "8443"
+	keySize             = 2048
+	certificateLifetime = 10 * 365 * 24 * time.Hour
+	pairingTimeout      = 30 * time.Second
+)
+
+type pairRequest struct {
+	Type        string `json:"@type"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	PrimaryRole string `json:"primaryRole"`
+	Certificate string `json:"certificate"`
+}
+
+// Pair registers the client certificate at the controller. The button on the
+// controller has to be pressed until its LEDs flash before calling Pair.
+func Pair(config *conf.Config, password string) error {
+	certPEM, err := ensureCertificate(config)
+	if err != nil {
+		return err
+	}
+	pairingURL, err := getPairingURL(config.BoschConfig)
+	if err != nil {
+		return err
+	}
+	requestBody, err := json.Marshal(pairRequest{
+		Type:        "client",
+		ID:          config.BoschConfig.ClientID,
+		Name:        config.BoschConfig.ClientName,
+		PrimaryRole: "ROLE_RESTRICTED_CLIENT",
+		Certificate: string(certPEM),
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pairingTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		pairingURL,
+		bytes.NewReader(requestBody),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Systempassword", base64.StdEncoding.EncodeToString([]byte(password)))
+
+	log.Info().
+		Str("url", pairingURL).
+		Str("clientID", config.BoschConfig.ClientID).
+		Msg("Registering client")
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				//nolint:gosec // https but only locally signed certificates
+				InsecureSkipVerify: true,
+			},
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := resp.Body.Close()
+		if e != nil {
+			log.Err(e).Msg("Error closing resp body")
+		}
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("pairing failed with status %d: %s. "+
+			"Check the system password and press the button on the controller until the LEDs flash",
+			resp.StatusCode, body,
+		)
+	}
+	log.Info().
+		Str("clientID", config.BoschConfig.ClientID).
+		Msg("Client registered")
+	return nil
+}
+
+func getPairingURL(config *conf.BoschConfig) (string, error) {
+	if config.PairingURL != "" {
+		return fmt.Sprintf("%s/smarthome/clients", config.PairingURL), nil
+	}
+	baseURL, err := url.Parse(config.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	baseURL.Host = net.JoinHostPort(baseURL.Hostname(), pairingPort)
+	return fmt.Sprintf("%s/smarthome/clients", baseURL.String()), nil
+}
+
+// ensureCertificate returns the PEM encoded client certificate and creates a
+// new self-signed one if neither certificate nor key exist yet.
+func ensureCertificate(config *conf.Config) ([]byte, error) {
+	certPEM, certErr := os.ReadFile(config.ClientCertPath)
+	_, keyErr := os.Stat(config.ClientKeyPath)
+	switch {
+	case certErr == nil && keyErr == nil:
+		log.Info().
+			Str("clientCertFile", config.ClientCertPath).
+			Msg("Using existing client certificate")
+		return certPEM, nil
+	case errors.Is(certErr, os.ErrNotExist) && errors.Is(keyErr, os.ErrNotExist):
+		return createCertificate(config)
+	case certErr != nil:
+		return nil, certErr
+	default:
+		return nil, keyErr
+	}
+}
+
+func createCertificate(config *conf.Config) ([]byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{CommonName: config.BoschConfig.ClientID},
+		NotBefore:             now,
+		NotAfter:              now.Add(certificateLifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if e := os.WriteFile(config.ClientKeyPath, keyPEM, 0o600); e != nil {
+		return nil, e
+	}
+	if e := os.WriteFile(config.ClientCertPath, certPEM, 0o644); e != nil {
+		return nil, e
+	}
+	log.Info().
+		Str("clientKeyFile", config.ClientKeyPath).
+		Str("clientCertFile", config.ClientCertPath).
+		Msg("Created new client certificate")
+	return certPEM, nil
+}
@@ -0,0 +1,79 @@
+package register
+
+import (
+	"bosch-data-exporter/internal/conf"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(t *testing.T, pairingURL string) *conf.Config {
+	dir := t.TempDir()
+	return &conf.Config{
+		ClientCertPath: filepath.Join(dir, "client-cert.pem"),
+		ClientKeyPath:  filepath.Join(dir, "client-key.pem"),
+		BoschConfig: &conf.BoschConfig{
+			ClientID:   "oss_test",
+			ClientName: "OSS Test",
+			BaseURL:    "https://localhost:8444",
+			PairingURL: pairingURL,
+		},
+	}
+}
+
+func TestPair(t *testing.T) {
+	var received pairRequest
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/smarthome/clients", r.URL.Path)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("secret")), r.Header.Get("Systempassword"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	config := testConfig(t, server.URL)
+
+	require.NoError(t, Pair(config, "secret"))
+
+	assert.Equal(t, "oss_test", received.ID)
+	assert.Equal(t, "OSS Test", received.Name)
+	assert.Equal(t, "ROLE_RESTRICTED_CLIENT", received.PrimaryRole)
+	certPEM, err := os.ReadFile(config.ClientCertPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(certPEM), received.Certificate)
+	_, err = tls.LoadX509KeyPair(config.ClientCertPath, config.ClientKeyPath)
+	assert.NoError(t, err)
+
+	require.NoError(t, Pair(config, "secret"))
+	assert.Equal(t, string(certPEM), received.Certificate, "existing certificate must be reused")
+}
+
+func TestPair_WrongPassword(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	assert.Error(t, Pair(testConfig(t, server.URL), "wrong"))
+}
+
+func TestPair_MissingKey(t *testing.T) {
+	config := testConfig(t, "https://localhost:1")
+	require.NoError(t, os.WriteFile(config.ClientCertPath, []byte("cert"), 0o600))
+
+	assert.ErrorIs(t, Pair(config, "secret"), os.ErrNotExist)
+}
+
+func TestGetPairingURL(t *testing.T) {
+	got, err := getPairingURL(&conf.BoschConfig{BaseURL: "https://shc1084ad:8444"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://shc1084ad:8443/smarthome/clients", got)
+}
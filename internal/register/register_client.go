@@ -11,8 +11,8 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func Register(client *http.Client, config *conf.Config) error {
-	clients, err := getRegisteredClients(client, config)
+func Register(ctx context.Context, client *http.Client, config *conf.Config) error {
+	clients, err := getRegisteredClients(ctx, client, config)
 	if err != nil {
 		log.Err(err).Msg("Error getting registered clients")
 		return err
@@ -30,8 +30,7 @@ func Register(client *http.Client, config *conf.Config) error {
 		}
 	}
 	return fmt.Errorf("client is not registered. " +
-		"Please Setup the CLient via the postman collection: " +
-		"https://github.com/BoschSmartHome/bosch-shc-api-docs/tree/master/postman",
+		"Please pair the client by running the pair command",
 	)
 }
 
@@ -48,9 +47,9 @@ type BoschClientResponse struct {
 	CreatedDate  string        `json:"createdDate"`
 }
 
-func getRegisteredClients(client *http.Client, config *conf.Config) ([]*BoschClientResponse, error) {
+func getRegisteredClients(ctx context.Context, client *http.Client, config *conf.Config) ([]*BoschClientResponse, error) {
 	req, err := http.NewRequestWithContext(
-		context.Background(),
+		ctx,
 		http.MethodGet,
 		fmt.Sprintf("%s/smarthome/clients", config.BoschConfig.BaseURL),
 		nil,
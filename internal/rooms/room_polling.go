@@ -59,11 +59,11 @@ func NewRoomPolling(client httpClient, config *conf.Config) *RoomPolling {
 	}
 }
 
-func (r *RoomPolling) Get() ([]*Room, error) {
+func (r *RoomPolling) Get(ctx context.Context) ([]*Room, error) {
 	timer := prometheus.NewTimer(r.reqDurationHist)
 	defer timer.ObserveDuration()
 	req, err := http.NewRequestWithContext(
-		context.Background(),
+		ctx,
 		http.MethodGet,
 		fmt.Sprintf("%s/smarthome/rooms", r.baseURL),
 		nil,
@@ -1,6 +1,7 @@
 package rooms
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -108,7 +109,7 @@ func TestRoomPolling_Get(t *testing.T) {
 				baseURL:         "http://localhost:8080",
 				reqDurationHist: nil,
 			}
-			got, err := r.Get()
+			got, err := r.Get(context.Background())
 			if !tt.wantErr(t, err, fmt.Sprintf("getSingle(%s)", tt.name)) {
 				return
 			}
@@ -0,0 +1,76 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown schedule format")
+
+// FormatOf returns the format of a schedule file by its extension.
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("%w: %s, use .yaml, .yml or .json", ErrUnknownFormat, path)
+	}
+}
+
+// Read reads and validates a schedule file.
+func Read(path string) (*File, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path) //nolint:gosec // the path is given by the user
+	if err != nil {
+		return nil, err
+	}
+	file := &File{}
+	if format == FormatYAML {
+		err = yaml.Unmarshal(content, file)
+	} else {
+		err = json.Unmarshal(content, file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing schedule file %s: %w", path, err)
+	}
+	if err = file.Validate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Write writes the schedule file in the given format.
+func Write(w io.Writer, file *File, format string) error {
+	switch format {
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(file); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(file)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
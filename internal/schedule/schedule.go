@@ -0,0 +1,250 @@
+// Package schedule converts the weekly heating schedules of the controller to
+// a readable file format and back.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	LevelComfort = "COMFORT"
+	LevelEco     = "ECO"
+
+	minutesPerDay   = 24 * 60
+	minTemperature  = 5.0
+	maxTemperature  = 30.0
+	temperatureStep = 0.5
+)
+
+// Schedule is the schedule of a RoomClimateControl as sent by the controller.
+type Schedule struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+type Profile struct {
+	Day          string        `json:"day"`
+	SwitchPoints []SwitchPoint `json:"switchPoints"`
+}
+
+type SwitchPoint struct {
+	StartTimeMinutes int   `json:"startTimeMinutes"`
+	Value            Value `json:"value"`
+}
+
+type Value struct {
+	Type             string `json:"@type"`
+	TemperatureLevel string `json:"temperatureLevel"`
+}
+
+// Days returns the days of the week in the order of the controller.
+func Days() []string {
+	return []string{"MONDAY", "TUESDAY", "WEDNESDAY", "THURSDAY", "FRIDAY", "SATURDAY", "SUNDAY"}
+}
+
+// LevelAt returns the temperature level that is active at the given time,
+// which has to be in the time zone of the controller. The last switch point
+// of the previous days applies before the first switch point of a day.
+func (s Schedule) LevelAt(t time.Time) (string, bool) {
+	days := Days()
+	today := (int(t.Weekday()) + len(days) - 1) % len(days)
+	limit := t.Hour()*60 + t.Minute()
+	for offset := 0; offset < len(days); offset++ {
+		day := days[(today-offset+len(days))%len(days)]
+		start, level := -1, ""
+		for _, p := range s.Profiles {
+			if p.Day != day {
+				continue
+			}
+			for _, sp := range p.SwitchPoints {
+				if sp.StartTimeMinutes <= limit && sp.StartTimeMinutes > start {
+					start, level = sp.StartTimeMinutes, sp.Value.TemperatureLevel
+				}
+			}
+		}
+		if start >= 0 {
+			return level, true
+		}
+		limit = minutesPerDay
+	}
+	return "", false
+}
+
+// File is the readable schedule of all rooms.
+type File struct {
+	Rooms []Room `json:"rooms" yaml:"rooms"`
+}
+
+// Room is the schedule of one room. The room is matched by ID and by name if
+// the ID is unknown, e.g. after a reset of the controller.
+type Room struct {
+	ID                 string  `json:"id" yaml:"id"`
+	Name               string  `json:"name" yaml:"name"`
+	ComfortTemperature float64 `json:"comfortTemperature,omitempty" yaml:"comfortTemperature,omitempty"`
+	EcoTemperature     float64 `json:"ecoTemperature,omitempty" yaml:"ecoTemperature,omitempty"`
+	Days               []Day   `json:"days" yaml:"days"`
+}
+
+type Day struct {
+	Day          string  `json:"day" yaml:"day"`
+	SwitchPoints []Point `json:"switchPoints" yaml:"switchPoints"`
+}
+
+// Point switches to a temperature level at a time of the day in HH:MM.
+type Point struct {
+	Time  string `json:"time" yaml:"time"`
+	Level string `json:"level" yaml:"level"`
+}
+
+// FromSchedule converts a schedule of the controller to readable days, ordered
+// from monday to sunday with sorted switch points.
+func FromSchedule(s Schedule) []Day {
+	days := make([]Day, 0, len(s.Profiles))
+	for _, p := range s.Profiles {
+		switchPoints := slices.Clone(p.SwitchPoints)
+		slices.SortStableFunc(switchPoints, func(a, b SwitchPoint) int {
+			return a.StartTimeMinutes - b.StartTimeMinutes
+		})
+		points := make([]Point, 0, len(switchPoints))
+		for _, sp := range switchPoints {
+			points = append(points, Point{
+				Time:  formatTime(sp.StartTimeMinutes),
+				Level: sp.Value.TemperatureLevel,
+			})
+		}
+		days = append(days, Day{Day: p.Day, SwitchPoints: points})
+	}
+	slices.SortStableFunc(days, func(a, b Day) int {
+		return slices.Index(Days(), a.Day) - slices.Index(Days(), b.Day)
+	})
+	return days
+}
+
+// Schedule converts the days of a validated room to a schedule of the
+// controller, ordered from monday to sunday.
+func (r *Room) Schedule() (Schedule, error) {
+	if err := r.Validate(); err != nil {
+		return Schedule{}, err
+	}
+	byDay := make(map[string]Day, len(r.Days))
+	for _, d := range r.Days {
+		byDay[d.Day] = d
+	}
+	profiles := make([]Profile, 0, len(r.Days))
+	for _, name := range Days() {
+		points := make([]SwitchPoint, 0, len(byDay[name].SwitchPoints))
+		for _, p := range byDay[name].SwitchPoints {
+			minutes, _ := parseTime(p.Time)
+			points = append(points, SwitchPoint{
+				StartTimeMinutes: minutes,
+				Value:            Value{Type: "temperatureLevelSwitchPointValue", TemperatureLevel: p.Level},
+			})
+		}
+		profiles = append(profiles, Profile{Day: name, SwitchPoints: points})
+	}
+	return Schedule{Profiles: profiles}, nil
+}
+
+// Validate returns all problems of the schedule file.
+func (f *File) Validate() error {
+	errs := make([]error, 0)
+	if len(f.Rooms) == 0 {
+		errs = append(errs, errors.New("no rooms"))
+	}
+	for i := range f.Rooms {
+		if err := f.Rooms[i].Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Room) Validate() error {
+	name := r.Name
+	if name == "" {
+		name = r.ID
+	}
+	errs := make([]error, 0)
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("room %s: "+format, append([]interface{}{name}, args...)...))
+	}
+	if r.ID == "" && r.Name == "" {
+		addErr("id or name is missing")
+	}
+	for _, t := range []struct {
+		name  string
+		value float64
+	}{{"comfortTemperature", r.ComfortTemperature}, {"ecoTemperature", r.EcoTemperature}} {
+		if t.value != 0 && !validTemperature(t.value) {
+			addErr("%s must be between %.1f and %.1f in steps of %.1f, but is %v",
+				t.name, minTemperature, maxTemperature, temperatureStep, t.value)
+		}
+	}
+	seen := make(map[string]bool, len(r.Days))
+	for _, d := range r.Days {
+		if !slices.Contains(Days(), d.Day) {
+			addErr("unknown day %q", d.Day)
+			continue
+		}
+		if seen[d.Day] {
+			addErr("%s is defined twice", d.Day)
+		}
+		seen[d.Day] = true
+		errs = append(errs, validateDay(name, d)...)
+	}
+	for _, d := range Days() {
+		if !seen[d] {
+			addErr("%s is missing", d)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateDay(room string, d Day) []error {
+	errs := make([]error, 0)
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("room %s, %s: "+format, append([]interface{}{room, d.Day}, args...)...))
+	}
+	if len(d.SwitchPoints) == 0 {
+		addErr("no switch points")
+	}
+	last := -1
+	for _, p := range d.SwitchPoints {
+		minutes, err := parseTime(p.Time)
+		if err != nil {
+			addErr("%v", err)
+			continue
+		}
+		if minutes <= last {
+			addErr("switch point %s is not after the previous one", p.Time)
+		}
+		last = minutes
+		if p.Level != LevelComfort && p.Level != LevelEco {
+			addErr("level of %s must be %s or %s, but is %q", p.Time, LevelComfort, LevelEco, p.Level)
+		}
+	}
+	return errs
+}
+
+func validTemperature(t float64) bool {
+	return t >= minTemperature && t <= maxTemperature && math.Mod(t, temperatureStep) == 0
+}
+
+func formatTime(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+func parseTime(value string) (int, error) {
+	var hours, minutes int
+	if n, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil || n != 2 || len(value) != len("00:00") {
+		return 0, fmt.Errorf("time %q is not in the format HH:MM", value)
+	}
+	result := hours*60 + minutes
+	if hours < 0 || minutes < 0 || minutes >= 60 || result >= minutesPerDay {
+		return 0, fmt.Errorf("time %q is not a time of the day", value)
+	}
+	return result, nil
+}
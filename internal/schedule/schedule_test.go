@@ -0,0 +1,164 @@
+package schedule
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func point(minutes int, level string) SwitchPoint {
+	return SwitchPoint{
+		StartTimeMinutes: minutes,
+		Value:            Value{Type: "temperatureLevelSwitchPointValue", TemperatureLevel: level},
+	}
+}
+
+func testRoom() Room {
+	days := make([]Day, 0, 7)
+	for _, d := range Days() {
+		days = append(days, Day{Day: d, SwitchPoints: []Point{{"06:00", LevelComfort}, {"22:30", LevelEco}}})
+	}
+	return Room{ID: "hz_1", Name: "Kitchen", ComfortTemperature: 21, EcoTemperature: 17.5, Days: days}
+}
+
+func TestFromSchedule(t *testing.T) {
+	days := FromSchedule(Schedule{Profiles: []Profile{
+		{Day: "TUESDAY", SwitchPoints: []SwitchPoint{point(1350, LevelEco), point(360, LevelComfort)}},
+		{Day: "MONDAY", SwitchPoints: []SwitchPoint{point(0, LevelEco)}},
+	}})
+	assert.Equal(t, []Day{
+		{Day: "MONDAY", SwitchPoints: []Point{{"00:00", LevelEco}}},
+		{Day: "TUESDAY", SwitchPoints: []Point{{"06:00", LevelComfort}, {"22:30", LevelEco}}},
+	}, days)
+}
+
+func TestSchedule_LevelAt(t *testing.T) {
+	s := Schedule{Profiles: []Profile{
+		{Day: "MONDAY", SwitchPoints: []SwitchPoint{point(1320, LevelEco), point(360, LevelComfort)}},
+		{Day: "WEDNESDAY", SwitchPoints: []SwitchPoint{point(480, LevelComfort), point(1080, LevelEco)}},
+	}}
+	tests := []struct {
+		name string
+		time time.Time
+		want string
+	}{
+		{name: "at switch point", time: time.Date(2026, 1, 5, 6, 0, 0, 0, time.UTC), want: LevelComfort},
+		{name: "before first switch point", time: time.Date(2026, 1, 5, 5, 59, 0, 0, time.UTC), want: LevelEco},
+		{name: "day without profile", time: time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC), want: LevelEco},
+		{name: "during day", time: time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC), want: LevelComfort},
+		{name: "end of day", time: time.Date(2026, 1, 7, 23, 59, 0, 0, time.UTC), want: LevelEco},
+		{name: "sunday wraps", time: time.Date(2026, 1, 11, 23, 0, 0, 0, time.UTC), want: LevelEco},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, ok := s.LevelAt(tt.time)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, level)
+		})
+	}
+	_, ok := Schedule{}.LevelAt(time.Now())
+	assert.False(t, ok)
+}
+
+func TestRoom_Schedule(t *testing.T) {
+	room := testRoom()
+	room.Days[0], room.Days[6] = room.Days[6], room.Days[0]
+	s, err := room.Schedule()
+	require.NoError(t, err)
+	require.Len(t, s.Profiles, 7)
+	assert.Equal(t, Profile{
+		Day:          "MONDAY",
+		SwitchPoints: []SwitchPoint{point(360, LevelComfort), point(1350, LevelEco)},
+	}, s.Profiles[0])
+	assert.Equal(t, "SUNDAY", s.Profiles[6].Day)
+	assert.Equal(t, room.Days[1:6], FromSchedule(s)[1:6])
+}
+
+func TestRoom_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(r *Room)
+		wantErr string
+	}{
+		{name: "valid", change: func(*Room) {}},
+		{name: "no temperatures", change: func(r *Room) { r.ComfortTemperature, r.EcoTemperature = 0, 0 }},
+		{name: "no id and name", change: func(r *Room) { r.ID, r.Name = "", "" }, wantErr: "id or name is missing"},
+		{
+			name:    "temperature",
+			change:  func(r *Room) { r.EcoTemperature = 17.2 },
+			wantErr: "room Kitchen: ecoTemperature must be between 5.0 and 30.0 in steps of 0.5, but is 17.2",
+		},
+		{name: "unknown day", change: func(r *Room) { r.Days[0].Day = "MON" }, wantErr: `room Kitchen: unknown day "MON"`},
+		{name: "missing day", change: func(r *Room) { r.Days = r.Days[1:] }, wantErr: "room Kitchen: MONDAY is missing"},
+		{name: "duplicate day", change: func(r *Room) { r.Days[1].Day = "MONDAY" }, wantErr: "MONDAY is defined twice"},
+		{
+			name:    "no switch points",
+			change:  func(r *Room) { r.Days[2].SwitchPoints = nil },
+			wantErr: "room Kitchen, WEDNESDAY: no switch points",
+		},
+		{
+			name:    "format",
+			change:  func(r *Room) { r.Days[0].SwitchPoints[0].Time = "6:00" },
+			wantErr: `time "6:00" is not in the format HH:MM`,
+		},
+		{
+			name:    "time of day",
+			change:  func(r *Room) { r.Days[0].SwitchPoints[1].Time = "24:00" },
+			wantErr: `time "24:00" is not a time of the day`,
+		},
+		{
+			name:    "order",
+			change:  func(r *Room) { r.Days[0].SwitchPoints[1].Time = "05:00" },
+			wantErr: "switch point 05:00 is not after the previous one",
+		},
+		{
+			name:    "level",
+			change:  func(r *Room) { r.Days[0].SwitchPoints[0].Level = "HOT" },
+			wantErr: `level of 06:00 must be COMFORT or ECO, but is "HOT"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := testRoom()
+			tt.change(&room)
+			err := room.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	file := &File{Rooms: []Room{testRoom()}}
+	for _, name := range []string{"schedule.yaml", "schedule.json"} {
+		t.Run(name, func(t *testing.T) {
+			format, err := FormatOf(name)
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, file, format))
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+			read, err := Read(path)
+			require.NoError(t, err)
+			assert.Equal(t, file, read)
+		})
+	}
+	_, err := FormatOf("schedule.txt")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestRead_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.yml")
+	require.NoError(t, os.WriteFile(path, []byte("rooms:\n  - name: Kitchen\n    days: []\n"), 0o600))
+	_, err := Read(path)
+	assert.ErrorContains(t, err, "room Kitchen: MONDAY is missing")
+}
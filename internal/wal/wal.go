@@ -0,0 +1,257 @@
+package wal
+
+import (
+	"bosch-data-exporter/internal/metrics"
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	segmentPrefix = "segment-"
+	segmentSuffix = ".lp"
+)
+
+var ErrNoSegment = errors.New("no sealed segment")
+
+type Options struct {
+	MaxBytes        int64
+	MaxAge          time.Duration
+	MaxSegmentBytes int64
+}
+
+type Segment struct {
+	Name  string
+	Lines []string
+}
+
+// Log is a write-ahead log of line protocol records stored as segment files.
+// Records are appended to the active segment. Sealed segments are handed out
+// oldest first until they are removed after a successful write.
+type Log struct {
+	dir             string
+	options         Options
+	active          *os.File
+	activeName      string
+	activeSize      int64
+	lastCreated     int64
+	lock            *sync.Mutex
+	backlogBytes    prometheus.Gauge
+	backlogSegments prometheus.Gauge
+	droppedSegments prometheus.Counter
+}
+
+// Open opens the log in dir. Logs opened again share their metrics.
+func Open(dir string, options Options) (*Log, error) {
+	return open(dir, options, metrics.NewGauge, metrics.NewCounter)
+}
+
+func open(
+	dir string,
+	options Options,
+	newGauge func(prometheus.GaugeOpts) prometheus.Gauge,
+	newCounter func(prometheus.CounterOpts) prometheus.Counter,
+) (*Log, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	l := &Log{
+		dir:     dir,
+		options: options,
+		lock:    &sync.Mutex{},
+		backlogBytes: newGauge(prometheus.GaugeOpts{
+			Name: "bosch_wal_backlog_bytes",
+			Help: "Size of all buffered segments not yet written",
+		}),
+		backlogSegments: newGauge(prometheus.GaugeOpts{
+			Name: "bosch_wal_backlog_segments",
+			Help: "Number of buffered segments not yet written",
+		}),
+		droppedSegments: newCounter(prometheus.CounterOpts{
+			Name: "bosch_wal_dropped_segments_total",
+			Help: "Number of buffered segments dropped because of size or age limits",
+		}),
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, err := l.enforceLimits(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) Append(lines ...string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.active == nil {
+		created := time.Now().UnixNano()
+		if created <= l.lastCreated {
+			created = l.lastCreated + 1
+		}
+		l.lastCreated = created
+		l.activeName = fmt.Sprintf("%s%020d%s", segmentPrefix, created, segmentSuffix)
+		file, err := os.OpenFile(filepath.Join(l.dir, l.activeName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		l.active = file
+		l.activeSize = 0
+	}
+	for _, line := range lines {
+		n, err := l.active.WriteString(line + "\n")
+		l.activeSize += int64(n)
+		l.backlogBytes.Add(float64(n))
+		if err != nil {
+			return err
+		}
+	}
+	if l.options.MaxSegmentBytes > 0 && l.activeSize >= l.options.MaxSegmentBytes {
+		return l.seal()
+	}
+	return nil
+}
+
+// Seal closes the active segment, so it is returned by Next.
+func (l *Log) Seal() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.seal()
+}
+
+func (l *Log) seal() error {
+	if l.active == nil {
+		return nil
+	}
+	err := l.active.Close()
+	l.active = nil
+	l.activeName = ""
+	l.backlogSegments.Inc()
+	return err
+}
+
+// Next returns the oldest sealed segment or ErrNoSegment if there is none.
+func (l *Log) Next() (*Segment, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	names, err := l.enforceLimits()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if name == l.activeName {
+			continue
+		}
+		lines, e := readLines(filepath.Join(l.dir, name))
+		if e != nil {
+			return nil, e
+		}
+		return &Segment{Name: name, Lines: lines}, nil
+	}
+	return nil, ErrNoSegment
+}
+
+func (l *Log) Remove(segment *Segment) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := os.Remove(filepath.Join(l.dir, segment.Name)); err != nil {
+		return err
+	}
+	_, err := l.enforceLimits()
+	return err
+}
+
+func (l *Log) Close() error {
+	return l.Seal()
+}
+
+// enforceLimits drops the oldest sealed segments exceeding the size or age
+// limits, updates the backlog metrics and returns the remaining segments.
+func (l *Log) enforceLimits() ([]string, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	sizes := make(map[string]int64)
+	total := int64(0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		info, e := entry.Info()
+		if e != nil {
+			return nil, e
+		}
+		names = append(names, name)
+		sizes[name] = info.Size()
+		total += info.Size()
+	}
+	sort.Strings(names)
+
+	for len(names) > 0 && names[0] != l.activeName && l.exceedsLimits(names[0], total) {
+		log.Warn().
+			Str("segment", names[0]).
+			Int64("backlogBytes", total).
+			Msg("Dropping buffered segment because of buffer limits")
+		if e := os.Remove(filepath.Join(l.dir, names[0])); e != nil {
+			return nil, e
+		}
+		total -= sizes[names[0]]
+		names = names[1:]
+		l.droppedSegments.Inc()
+	}
+
+	l.backlogBytes.Set(float64(total))
+	sealed := len(names)
+	if l.active != nil {
+		sealed--
+	}
+	l.backlogSegments.Set(float64(sealed))
+	return names, nil
+}
+
+func (l *Log) exceedsLimits(name string, total int64) bool {
+	if l.options.MaxBytes > 0 && total > l.options.MaxBytes {
+		return true
+	}
+	if l.options.MaxAge <= 0 {
+		return false
+	}
+	created, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+	if err != nil {
+		return false
+	}
+	return time.Since(time.Unix(0, created)) > l.options.MaxAge
+}
+
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := file.Close()
+		if e != nil {
+			log.Err(e).Str("path", path).Msg("Error closing segment")
+		}
+	}()
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
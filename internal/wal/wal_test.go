@@ -0,0 +1,81 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_AppendAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	l, err := open(dir, Options{}, prometheus.NewGauge, prometheus.NewCounter)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Append("m v=1i 1", "m v=2i 2"))
+	_, err = l.Next()
+	assert.ErrorIs(t, err, ErrNoSegment, "active segment must not be replayed")
+
+	require.NoError(t, l.Seal())
+	require.NoError(t, l.Append("m v=3i 3"))
+	require.NoError(t, l.Close())
+	assert.Equal(t, float64(2), testutil.ToFloat64(l.backlogSegments))
+
+	reopened, err := open(dir, Options{}, prometheus.NewGauge, prometheus.NewCounter)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(reopened.backlogSegments))
+
+	first, err := reopened.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m v=1i 1", "m v=2i 2"}, first.Lines)
+	require.NoError(t, reopened.Remove(first))
+
+	second, err := reopened.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m v=3i 3"}, second.Lines)
+	require.NoError(t, reopened.Remove(second))
+
+	_, err = reopened.Next()
+	assert.ErrorIs(t, err, ErrNoSegment)
+	assert.Equal(t, float64(0), testutil.ToFloat64(reopened.backlogBytes))
+}
+
+func TestLog_MaxSegmentBytes(t *testing.T) {
+	l, err := open(t.TempDir(), Options{MaxSegmentBytes: 10}, prometheus.NewGauge, prometheus.NewCounter)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Append("m v=1i 1"))
+	require.NoError(t, l.Append("m v=2i 2"))
+
+	segment, err := l.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m v=1i 1", "m v=2i 2"}, segment.Lines)
+}
+
+func TestLog_Limits(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "segment-00000000000000000001.lp")
+	require.NoError(t, os.WriteFile(old, []byte("m v=1i 1\n"), 0o600))
+
+	l, err := open(dir, Options{MaxAge: time.Hour, MaxBytes: 20}, prometheus.NewGauge, prometheus.NewCounter)
+	require.NoError(t, err)
+	assert.NoFileExists(t, old)
+	assert.Equal(t, float64(1), testutil.ToFloat64(l.droppedSegments))
+
+	require.NoError(t, l.Append("m v=2i 2"))
+	require.NoError(t, l.Seal())
+	require.NoError(t, l.Append("m v=3i 3"))
+	require.NoError(t, l.Seal())
+	require.NoError(t, l.Append("m v=4i 4"))
+	require.NoError(t, l.Seal())
+
+	segment, err := l.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m v=3i 3"}, segment.Lines)
+	assert.Equal(t, float64(2), testutil.ToFloat64(l.droppedSegments))
+}